@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dosco/graphjin/core/v3"
+)
+
+// APIKeyConfig is the config for the api key authentication, the keys are
+// stored hashed in a database table with the following columns:
+//
+//	CREATE TABLE api_keys (
+//		id varchar(32) NOT NULL PRIMARY KEY,
+//		key_hash varchar(64) NOT NULL UNIQUE,
+//		user_id text NOT NULL,
+//		role text NOT NULL DEFAULT '',
+//		scopes text NOT NULL DEFAULT '',
+//		revoked boolean NOT NULL DEFAULT false,
+//		expires_at timestamp NULL,
+//		created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+//	);
+type APIKeyConfig struct {
+	// Header is the HTTP header that holds the api key
+	Header string `jsonschema:"title=API Key Header,default=X-API-Key"`
+
+	// Table is the database table that holds the hashed api keys
+	Table string `jsonschema:"title=API Keys Table,default=api_keys"`
+
+	// CacheTTL is how long a key is cached after it is looked up
+	CacheTTL time.Duration `mapstructure:"cache_ttl" jsonschema:"title=API Key Cache TTL,default=1m"`
+}
+
+// APIKey holds the user, role and scopes an api key is mapped to
+type APIKey struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Role      string     `json:"role,omitempty"`
+	Scopes    []string   `json:"scopes,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+var tableRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+// APIKeyStore looks up the api keys in the database, the keys are cached
+// so the database is not queried on every request. Unknown keys are not
+// cached so that new keys can be used right away.
+type APIKeyStore struct {
+	db     *sql.DB
+	dbType string
+	table  string
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	cache map[string]apiKeyEntry
+	swept time.Time
+}
+
+type apiKeyEntry struct {
+	key APIKey
+	exp time.Time
+}
+
+// NewAPIKeyStore returns a new api key store that uses the provided database
+func NewAPIKeyStore(db *sql.DB, dbType string, conf APIKeyConfig) (*APIKeyStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("no database defined for the api keys")
+	}
+
+	table := conf.Table
+	if table == "" {
+		table = "api_keys"
+	}
+	if !tableRe.MatchString(table) {
+		return nil, fmt.Errorf("invalid api keys table: %s", table)
+	}
+
+	ttl := conf.CacheTTL
+	if ttl == 0 {
+		ttl = time.Minute
+	}
+
+	return &APIKeyStore{
+		db:     db,
+		dbType: dbType,
+		table:  table,
+		ttl:    ttl,
+		cache:  make(map[string]apiKeyEntry),
+	}, nil
+}
+
+// Get returns the api key, revoked and expired keys are not returned
+func (s *APIKeyStore) Get(c context.Context, key string) (APIKey, error) {
+	hash := hashAPIKey(key)
+	now := time.Now()
+
+	s.mu.Lock()
+	e, ok := s.cache[hash]
+	s.mu.Unlock()
+
+	if ok && now.Before(e.exp) {
+		return e.key, nil
+	}
+
+	var k APIKey
+	var scopes string
+	var exp sql.NullTime
+
+	q := s.query(`SELECT id, user_id, role, scopes, expires_at FROM ` + s.table +
+		` WHERE key_hash = $1 AND revoked = false`)
+
+	err := s.db.QueryRowContext(c, q, hash).
+		Scan(&k.ID, &k.UserID, &k.Role, &scopes, &exp)
+	if err == sql.ErrNoRows {
+		return k, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return k, err
+	}
+
+	if exp.Valid {
+		if !now.Before(exp.Time) {
+			return APIKey{}, ErrAPIKeyNotFound
+		}
+		k.ExpiresAt = &exp.Time
+	}
+	k.Scopes = strings.Fields(scopes)
+
+	// keys are not cached past their expiry
+	e = apiKeyEntry{key: k, exp: now.Add(s.ttl)}
+	if k.ExpiresAt != nil && k.ExpiresAt.Before(e.exp) {
+		e.exp = *k.ExpiresAt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// remove the expired keys from the cache
+	if now.Sub(s.swept) > time.Minute {
+		for h, v := range s.cache {
+			if !now.Before(v.exp) {
+				delete(s.cache, h)
+			}
+		}
+		s.swept = now
+	}
+	s.cache[hash] = e
+	return k, nil
+}
+
+// Create adds a new api key for a user and returns the key, only the hash
+// of the key is saved so the key cannot be fetched again
+func (s *APIKeyStore) Create(c context.Context, k APIKey) (APIKey, string, error) {
+	if k.UserID == "" {
+		return k, "", fmt.Errorf("api key: user_id is required")
+	}
+
+	id, err := randomBytes(8)
+	if err != nil {
+		return k, "", err
+	}
+	key, err := newAPIKey()
+	if err != nil {
+		return k, "", err
+	}
+	k.ID = hex.EncodeToString(id)
+
+	var exp sql.NullTime
+	if k.ExpiresAt != nil {
+		exp = sql.NullTime{Time: *k.ExpiresAt, Valid: true}
+	}
+
+	q := s.query(`INSERT INTO ` + s.table +
+		` (id, key_hash, user_id, role, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6)`)
+
+	_, err = s.db.ExecContext(c, q,
+		k.ID, hashAPIKey(key), k.UserID, k.Role, strings.Join(k.Scopes, " "), exp)
+	if err != nil {
+		return k, "", err
+	}
+	return k, key, nil
+}
+
+// Rotate replaces an api key with a new one and returns the new key,
+// the old key stops working right away
+func (s *APIKeyStore) Rotate(c context.Context, id string) (string, error) {
+	key, err := newAPIKey()
+	if err != nil {
+		return "", err
+	}
+
+	q := s.query(`UPDATE ` + s.table +
+		` SET key_hash = $1 WHERE id = $2 AND revoked = false`)
+
+	if err := s.exec(c, q, hashAPIKey(key), id); err != nil {
+		return "", err
+	}
+	s.evict(id)
+	return key, nil
+}
+
+// Revoke revokes an api key
+func (s *APIKeyStore) Revoke(c context.Context, id string) error {
+	q := s.query(`UPDATE ` + s.table +
+		` SET revoked = true WHERE id = $1 AND revoked = false`)
+
+	if err := s.exec(c, q, id); err != nil {
+		return err
+	}
+	s.evict(id)
+	return nil
+}
+
+// exec executes a statement that must update a key
+func (s *APIKeyStore) exec(c context.Context, q string, args ...interface{}) error {
+	res, err := s.db.ExecContext(c, q, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// evict removes a key from the cache, other instances of the service
+// keep using their cached key until the cache ttl has passed
+func (s *APIKeyStore) evict(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for h, v := range s.cache {
+		if v.key.ID == id {
+			delete(s.cache, h)
+		}
+	}
+}
+
+var paramRe = regexp.MustCompile(`\$\d+`)
+
+// query returns the query with the placeholders of the database
+func (s *APIKeyStore) query(q string) string {
+	if s.dbType == "mysql" {
+		return paramRe.ReplaceAllString(q, "?")
+	}
+	return q
+}
+
+// APIKeyHandler returns a handler that authenticates using an api key
+func APIKeyHandler(ac Auth, store *APIKeyStore) (HandlerFunc, error) {
+	if store == nil {
+		return nil, fmt.Errorf("no database defined for the api keys")
+	}
+
+	hdr := ac.APIKey.Header
+	if hdr == "" {
+		hdr = "X-API-Key"
+	}
+
+	return func(_ http.ResponseWriter, r *http.Request) (context.Context, error) {
+		v := r.Header.Get(hdr)
+		if v == "" {
+			return nil, nil
+		}
+
+		k, err := store.Get(r.Context(), v)
+		if err == ErrAPIKeyNotFound {
+			return nil, Err401
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c := context.WithValue(r.Context(), core.UserIDKey, k.UserID)
+		c = context.WithValue(c, apiKeyIDKey, k.ID)
+
+		if k.Role != "" {
+			c = context.WithValue(c, core.UserRoleKey, k.Role)
+		}
+
+		if len(k.Scopes) != 0 {
+			c = context.WithValue(c, core.UserScopesKey, k.Scopes)
+		}
+		return c, nil
+	}, nil
+}
+
+// APIKeyID returns the ID of the api key the request was authenticated with
+func APIKeyID(c context.Context) (string, bool) {
+	v, ok := c.Value(apiKeyIDKey).(string)
+	return v, ok
+}
+
+// newAPIKey returns a new random api key
+func newAPIKey() (string, error) {
+	b, err := randomBytes(32)
+	if err != nil {
+		return "", err
+	}
+	return "gj_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// randomBytes returns n random bytes
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+// hashAPIKey returns the hash of the key that is saved in the database
+func hashAPIKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
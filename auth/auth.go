@@ -35,10 +35,12 @@ package auth
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/dosco/graphjin/core/v3"
 	"github.com/gorilla/websocket"
@@ -48,7 +50,11 @@ import (
 	"github.com/dosco/graphjin/auth/v3/provider"
 )
 
-type JWTConfig = provider.JWTConfig
+type (
+	JWTConfig           = provider.JWTConfig
+	IntrospectionConfig = provider.IntrospectionConfig
+	OIDCIssuer          = provider.OIDCIssuer
+)
 
 // Auth struct contains authentication related config values used by the GraphJin service
 type Auth struct {
@@ -58,19 +64,19 @@ type Auth struct {
 	// Name is a friendly name for this auth config
 	Name string
 
-	// Type can be one of rails, jwt or header
-	Type string `jsonschema:"title=Type,enum=jwt,enum=rails,enum=header"`
+	// Type can be one of rails, jwt, introspection, header, apikey, mtls, hmac or chain
+	Type string `jsonschema:"title=Type,enum=jwt,enum=rails,enum=introspection,enum=header,enum=apikey,enum=mtls,enum=hmac,enum=chain"`
 
-	// The name of the cookie that holds the authentication token
-	Cookie string `jsonschema:"title=Cookie Name"`
+	// Role overrides the role of the users authenticated by this auth method
+	// when used in a chain
+	Role string `jsonschema:"title=Role Override"`
 
-	// In certain cases like Magiclink the jwt cookie is generated by us this
-	// set the secure parameter of this cookie
-	// CookieHTTPS bool `mapstructure:"cookie_https"`
+	// Methods is the ordered list of auth methods tried by the chain auth type,
+	// the first method that authenticates the request is used
+	Methods []Auth `jsonschema:"title=Chained Auth Methods"`
 
-	// In certain cases like Magiclink the jwt cookie is generated by us this
-	// set the expiry parameter of this cookie (ex. "20m", "2h")
-	// CookieExpiry string `mapstructure:"cookie_expiry"`
+	// The name of the cookie that holds the authentication token
+	Cookie string `jsonschema:"title=Cookie Name"`
 
 	// Ruby on Rails cookie authentication
 	Rails struct {
@@ -88,6 +94,14 @@ type Auth struct {
 		// Password is set if needed by the cookie store (Redis, Memcache, etc)
 		Password string
 
+		// SessionTable is used for database session store based auth, it's the
+		// table of the activerecord-session_store gem. Example: sessions
+		SessionTable string `mapstructure:"session_table" jsonschema:"title=Database Session Table,example=sessions"`
+
+		// SessionMaxAge rejects database sessions that have not been updated
+		// for longer than this, it uses the updated_at column of the table
+		SessionMaxAge time.Duration `mapstructure:"session_max_age" jsonschema:"title=Database Session Maximum Age,example=24h"`
+
 		// Maximum idle time for the connection
 		MaxIdle int `mapstructure:"max_idle" jsonschema:"title=Cookie Store Maximum Idle Time"`
 
@@ -107,6 +121,12 @@ type Auth struct {
 	// JWT authentication
 	JWT JWTConfig
 
+	// Built-in login that issues jwt tokens for one-time codes
+	Login LoginConfig
+
+	// OAuth2 token introspection authentication
+	Introspection IntrospectionConfig
+
 	// Header authentication
 	Header struct {
 		// Name of the HTTP header
@@ -120,10 +140,14 @@ type Auth struct {
 		Exists bool
 	}
 
-	// Magic.link authentication
-	// MagicLink struct {
-	// 	Secret string
-	// }
+	// API key authentication
+	APIKey APIKeyConfig `mapstructure:"apikey" jsonschema:"title=API Key"`
+
+	// Mutual TLS client certificate authentication
+	MTLS MTLSConfig `mapstructure:"mtls" jsonschema:"title=Mutual TLS"`
+
+	// Signed request (HMAC) authentication
+	HMAC HMACConfig `mapstructure:"hmac" jsonschema:"title=Signed Requests (HMAC)"`
 }
 
 type HandlerFunc func(w http.ResponseWriter, r *http.Request) (context.Context, error)
@@ -133,13 +157,53 @@ type Options struct {
 	AuthFailBlock bool
 }
 
+// HandlerOption sets the dependencies of the auth handlers
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	db      *sql.DB
+	dbType  string
+	apiKeys *APIKeyStore
+	secrets func(string) string
+}
+
+// OptionSetDB sets the database used by the auth handlers that
+// keep their data in the database like apikey
+func OptionSetDB(db *sql.DB, dbType string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.db = db
+		o.dbType = dbType
+	}
+}
+
+// OptionSetAPIKeyStore sets the store used by the apikey auth handler,
+// this is needed to share the store with the code that manages the keys
+func OptionSetAPIKeyStore(store *APIKeyStore) HandlerOption {
+	return func(o *handlerOptions) {
+		o.apiKeys = store
+	}
+}
+
+// OptionSetSecrets sets the function used by the auth handlers to look up
+// secrets by name, by default the secrets are read from the environment
+func OptionSetSecrets(fn func(key string) string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.secrets = fn
+	}
+}
+
 // NewAuthHandlerFunc returns a HandlerFunc based on the provided config.
 // Usually you don't need to use this function, because is called by NewAuth if
 // no HandlerFunc is provided.
-func NewAuthHandlerFunc(ac Auth) (HandlerFunc, error) {
+func NewAuthHandlerFunc(ac Auth, opts ...HandlerOption) (HandlerFunc, error) {
 	var h HandlerFunc
 	var err error
 
+	var o handlerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	switch ac.Development {
 	case true:
 		h, err = SimpleHandler(ac)
@@ -147,16 +211,34 @@ func NewAuthHandlerFunc(ac Auth) (HandlerFunc, error) {
 	default:
 		switch ac.Type {
 		case "rails":
-			h, err = RailsHandler(ac)
+			h, err = RailsHandler(ac, opts...)
 
 		case "jwt":
 			h, err = JwtHandler(ac)
 
+		case "introspection":
+			h, err = IntrospectionHandler(ac)
+
 		case "header":
 			h, err = HeaderHandler(ac)
 
-		// case "magiclink":
-		// 	h, err = MagicLinkHandler(ac, next)
+		case "apikey":
+			store := o.apiKeys
+			if store == nil {
+				store, err = NewAPIKeyStore(o.db, o.dbType, ac.APIKey)
+			}
+			if err == nil {
+				h, err = APIKeyHandler(ac, store)
+			}
+
+		case "mtls":
+			h, err = MTLSHandler(ac)
+
+		case "hmac":
+			h, err = HMACHandler(ac, o.secrets)
+
+		case "chain":
+			h, err = ChainHandler(ac, opts...)
 
 		case "", "none":
 			h, err = NoAuth()
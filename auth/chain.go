@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/dosco/graphjin/core/v3"
+)
+
+type contextkey int
+
+const (
+	// methodKey holds the name of the auth method that authenticated the request
+	methodKey contextkey = iota
+
+	// apiKeyIDKey holds the ID of the api key that authenticated the request
+	apiKeyIDKey
+)
+
+// Method returns the name of the auth method that authenticated the request,
+// it's only set when using an auth chain
+func Method(c context.Context) string {
+	if c == nil {
+		return ""
+	}
+	v, _ := c.Value(methodKey).(string)
+	return v
+}
+
+type chainMethod struct {
+	name string
+	ac   Auth
+	h    HandlerFunc
+}
+
+// ChainHandler returns a handler that tries the auth methods in order and
+// uses the first one that authenticates the request. A method whose credential
+// is present but rejected stops the chain, other errors do not. The request
+// is unauthenticated when none of the methods succeed.
+func ChainHandler(ac Auth, opts ...HandlerOption) (HandlerFunc, error) {
+	if len(ac.Methods) == 0 {
+		return nil, fmt.Errorf("auth '%s': no methods defined", ac.Name)
+	}
+
+	methods := make([]chainMethod, 0, len(ac.Methods))
+
+	for i, m := range ac.Methods {
+		name := m.Name
+		if name == "" {
+			name = m.Type
+		}
+
+		switch m.Type {
+		case "", "none":
+			return nil, fmt.Errorf("auth '%s': method %d: no type defined", ac.Name, i)
+		case "chain":
+			return nil, fmt.Errorf("auth '%s': method '%s': chains cannot be nested", ac.Name, name)
+		}
+
+		h, err := NewAuthHandlerFunc(m, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("auth '%s': method '%s': %w", ac.Name, name, err)
+		}
+		methods = append(methods, chainMethod{name: name, ac: m, h: h})
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+		var err1 error
+
+		for _, m := range methods {
+			c, err := m.h(w, r)
+			if err == Err401 && m.hasCredential(r) {
+				return nil, Err401
+			}
+			if err != nil {
+				if err != Err401 && err1 == nil {
+					err1 = fmt.Errorf("%s: %w", m.name, err)
+				}
+				continue
+			}
+
+			// header auth does not set a user, it succeeds when no error is returned
+			if !IsAuth(c) && m.ac.Type != "header" {
+				continue
+			}
+
+			if c == nil {
+				c = r.Context()
+			}
+			if m.ac.Role != "" {
+				c = context.WithValue(c, core.UserRoleKey, m.ac.Role)
+			}
+			return context.WithValue(c, methodKey, m.name), nil
+		}
+		return nil, err1
+	}, nil
+}
+
+// hasCredential returns true if the request has the credential of the method,
+// the methods return no error when their credential is missing except for the
+// header auth which fails when its header is missing
+func (m chainMethod) hasCredential(r *http.Request) bool {
+	if m.ac.Type == "header" {
+		return r.Header.Get(m.ac.Header.Name) != ""
+	}
+	return true
+}
+
+// FindType returns the auth config of the type, either the auth
+// config itself or one of the methods of its chain
+func (ac Auth) FindType(t string) (Auth, bool) {
+	if ac.Type == t {
+		return ac, true
+	}
+	if ac.Type == "chain" {
+		for _, m := range ac.Methods {
+			if m.Type == t {
+				return m, true
+			}
+		}
+	}
+	return Auth{}, false
+}
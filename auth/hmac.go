@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dosco/graphjin/core/v3"
+)
+
+// HMACConfig is the config for the signed request authentication. The
+// signature is the hex encoded HMAC-SHA256 of the following lines joined
+// with a newline: method, path (with the query string), timestamp, nonce
+// and the request body.
+type HMACConfig struct {
+	// Header is the HTTP header that holds the signature
+	Header string `jsonschema:"title=Signature Header,default=X-Signature"`
+
+	// KeyHeader is the HTTP header that holds the ID of the key used to sign
+	KeyHeader string `mapstructure:"key_header" jsonschema:"title=Key ID Header,default=X-Key-ID"`
+
+	// TimestampHeader is the HTTP header that holds the unix timestamp of the request
+	TimestampHeader string `mapstructure:"timestamp_header" jsonschema:"title=Timestamp Header,default=X-Timestamp"`
+
+	// NonceHeader is the HTTP header that holds the unique nonce of the request
+	NonceHeader string `mapstructure:"nonce_header" jsonschema:"title=Nonce Header,default=X-Nonce"`
+
+	// MaxAge is how far the timestamp can be from the current time
+	MaxAge time.Duration `mapstructure:"max_age" jsonschema:"title=Maximum Request Age,default=5m"`
+
+	// Keys are the shared keys the requests can be signed with
+	Keys []HMACKey
+}
+
+// HMACKey is a shared key and the user it's mapped to
+type HMACKey struct {
+	// ID is sent by the client in the key header
+	ID string
+
+	// Secret is the name of the secret holding the shared key, it's read from
+	// the secrets file or the environment. Example: WEBHOOK_STRIPE_SECRET
+	Secret string
+
+	// UserID is the user the requests are made as, defaults to the key ID
+	UserID string `mapstructure:"user_id"`
+
+	// Role is the role the requests are made as
+	Role string
+}
+
+// maxSignedBody is the largest request body that can be signed
+const maxSignedBody = 1 << 20
+
+type hmacKey struct {
+	HMACKey
+	secret []byte
+}
+
+// HMACHandler returns a handler that authenticates requests signed with a
+// shared key. A nonce can only be used once within the max age window.
+func HMACHandler(ac Auth, secrets func(string) string) (HandlerFunc, error) {
+	conf := ac.HMAC
+
+	if conf.Header == "" {
+		conf.Header = "X-Signature"
+	}
+	if conf.KeyHeader == "" {
+		conf.KeyHeader = "X-Key-ID"
+	}
+	if conf.TimestampHeader == "" {
+		conf.TimestampHeader = "X-Timestamp"
+	}
+	if conf.NonceHeader == "" {
+		conf.NonceHeader = "X-Nonce"
+	}
+	if conf.MaxAge == 0 {
+		conf.MaxAge = 5 * time.Minute
+	}
+
+	if len(conf.Keys) == 0 {
+		return nil, fmt.Errorf("no keys defined")
+	}
+	if secrets == nil {
+		secrets = os.Getenv
+	}
+
+	keys := make(map[string]hmacKey, len(conf.Keys))
+
+	for _, k := range conf.Keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("key id is required")
+		}
+		if k.Secret == "" {
+			return nil, fmt.Errorf("key '%s': no secret defined", k.ID)
+		}
+		v := secrets(k.Secret)
+		if v == "" {
+			return nil, fmt.Errorf("key '%s': secret not found: %s", k.ID, k.Secret)
+		}
+		if k.UserID == "" {
+			k.UserID = k.ID
+		}
+		keys[k.ID] = hmacKey{HMACKey: k, secret: []byte(v)}
+	}
+
+	nonces := newNonceCache(2 * conf.MaxAge)
+
+	return func(_ http.ResponseWriter, r *http.Request) (context.Context, error) {
+		sig := r.Header.Get(conf.Header)
+		if sig == "" {
+			return nil, nil
+		}
+		sig = strings.TrimPrefix(sig, "sha256=")
+
+		k, ok := keys[r.Header.Get(conf.KeyHeader)]
+		if !ok {
+			return nil, Err401
+		}
+
+		ts := r.Header.Get(conf.TimestampHeader)
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return nil, Err401
+		}
+		if d := time.Since(time.Unix(sec, 0)); d > conf.MaxAge || d < -conf.MaxAge {
+			return nil, errors.New("signed request expired")
+		}
+
+		nonce := r.Header.Get(conf.NonceHeader)
+		if nonce == "" {
+			return nil, Err401
+		}
+
+		var body []byte
+		if r.Body != nil {
+			body, err = io.ReadAll(io.LimitReader(r.Body, maxSignedBody+1))
+			if err != nil {
+				return nil, err
+			}
+			if len(body) > maxSignedBody {
+				return nil, errors.New("signed request body too large")
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		mac, err := hex.DecodeString(sig)
+		if err != nil {
+			return nil, Err401
+		}
+		if !hmac.Equal(mac, SignRequest(k.secret, r.Method, r.URL.RequestURI(), ts, nonce, body)) {
+			return nil, Err401
+		}
+
+		// the nonce is only saved for requests with a valid signature
+		if !nonces.add(k.ID + ":" + nonce) {
+			return nil, errors.New("signed request replayed")
+		}
+
+		c := context.WithValue(r.Context(), core.UserIDKey, k.UserID)
+
+		if k.Role != "" {
+			c = context.WithValue(c, core.UserRoleKey, k.Role)
+		}
+		return c, nil
+	}, nil
+}
+
+// SignRequest returns the HMAC-SHA256 signature of the request, the
+// signature header is the hex encoded value of this
+func SignRequest(secret []byte, method, path, timestamp, nonce string, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(method + "\n" + path + "\n" + timestamp + "\n" + nonce + "\n"))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// nonceCache holds the nonces used until they expire
+type nonceCache struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	swept  time.Time
+}
+
+func newNonceCache(ttl time.Duration) *nonceCache {
+	return &nonceCache{ttl: ttl, nonces: make(map[string]time.Time)}
+}
+
+// add saves the nonce and returns false if it was already used
+func (nc *nonceCache) add(nonce string) bool {
+	now := time.Now()
+
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if exp, ok := nc.nonces[nonce]; ok && now.Before(exp) {
+		return false
+	}
+
+	// remove the expired nonces from the cache
+	if now.Sub(nc.swept) > time.Minute {
+		for k, exp := range nc.nonces {
+			if !now.Before(exp) {
+				delete(nc.nonces, k)
+			}
+		}
+		nc.swept = now
+	}
+	nc.nonces[nonce] = now.Add(nc.ttl)
+	return true
+}
@@ -1,6 +1,7 @@
 package rails
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -98,6 +99,27 @@ func ParseCookie(cookie string) (string, error) {
 	return getUserId([]byte(cookie))
 }
 
+// ParseSession parses the session data stored in the database by the
+// activerecord-session_store gem and returns the user ID. The data is
+// either json or base64 encoded ruby marshal data.
+func ParseSession(data string) (string, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return "", errSessionData
+	}
+
+	if data[0] == '{' {
+		return getUserId([]byte(data))
+	}
+
+	// base64 encoded data from ruby has line breaks
+	b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+	if err != nil {
+		return "", err
+	}
+	return getUserId4(b)
+}
+
 // getUserId extracts the user ID from the session data
 func getUserId(data []byte) (userID string, err error) {
 	var sessionData map[string]interface{}
@@ -78,3 +78,27 @@ func TestRailsMarshaledSession(t *testing.T) {
 		t.Errorf("Expecting userID 2 got %s", userID)
 	}
 }
+
+func TestRailsDBSession(t *testing.T) {
+	sessions := []string{
+		`{"warden.user.user.key":[[1],"secret"]}`,
+
+		// base64 encoded marshal data with line breaks
+		"BAh7CEkiFW1lbWJlcl9yZXR1cm5fdG8GOgZFVEkiBi8GOwBUSSIZd2FyZGVuLnVzZXIudXNlci5r\n" +
+			"ZXkGOwBUWwdbBmkGSSIiJDJhJDExJDZTZ1hkdk85aGxkODJrUUF2cEVZM2UGOwBUSSIQX2NzcmZf\n" +
+			"dG9rZW4GOwBGSSIxN2xxd2oxVXNUVGdiWEJRS0g0aXBDTlczMnVMdXN2ZlNQZHMxdHhwcE1lYz0G\n" +
+			"OwBG\n",
+	}
+
+	for _, v := range sessions {
+		userID, err := ParseSession(v)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if userID != "1" {
+			t.Errorf("Expecting userID 1 got %s", userID)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dosco/graphjin/auth/v3/provider"
+)
+
+// IntrospectionHandler returns a handler that authenticates using an opaque
+// access token which is validated with an OAuth2 token introspection endpoint
+func IntrospectionHandler(ac Auth) (HandlerFunc, error) {
+	p, err := provider.NewIntrospectionProvider(ac.Introspection)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie := ac.Cookie
+
+	return func(_ http.ResponseWriter, r *http.Request) (context.Context, error) {
+		tok := tokenFromRequest(r, cookie)
+		if tok == "" {
+			return nil, nil
+		}
+
+		in, err := p.Introspect(r.Context(), tok)
+		if err != nil {
+			return nil, err
+		}
+
+		if !in.Active() {
+			return nil, Err401
+		}
+		return p.SetContextValues(r.Context(), in)
+	}, nil
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	jwt "github.com/golang-jwt/jwt"
 
 	"github.com/dosco/graphjin/auth/v3/provider"
+	"github.com/dosco/graphjin/core/v3"
 )
 
 const (
@@ -25,22 +27,10 @@ func JwtHandler(ac Auth) (HandlerFunc, error) {
 	cookie := ac.Cookie
 
 	return func(w http.ResponseWriter, r *http.Request) (context.Context, error) {
-		var tok string
-
-		if cookie != "" {
-			if ck, err := r.Cookie(cookie); err == nil && len(ck.Value) != 0 {
-				tok = ck.Value
-			}
-		}
-
+		// no token is not an error, the request is anonymous
+		tok := tokenFromRequest(r, cookie)
 		if tok == "" {
-			if ah := r.Header.Get(authHeader); len(ah) > 10 {
-				tok = ah[7:]
-			}
-		}
-
-		if tok == "" {
-			return nil, fmt.Errorf("no jwt token found in cookie or authorization header")
+			return nil, nil
 		}
 
 		keyFunc := jwtProvider.KeyFunc()
@@ -60,9 +50,56 @@ func JwtHandler(ac Auth) (HandlerFunc, error) {
 				return nil, fmt.Errorf("invalid iss claim")
 			}
 
+			if rv, ok := jwtProvider.(provider.RequestVerifier); ok {
+				if err := rv.VerifyRequest(r, claims); err != nil {
+					return nil, err
+				}
+			}
+
 			ctx, err = jwtProvider.SetContextValues(ctx, claims)
-			return ctx, err
+			if err != nil {
+				return ctx, err
+			}
+
+			if scopes := claimScopes(claims); len(scopes) != 0 {
+				ctx = context.WithValue(ctx, core.UserScopesKey, scopes)
+			}
+			ctx = context.WithValue(ctx, core.UserClaimsKey, map[string]interface{}(claims))
+			return ctx, nil
 		}
 		return nil, fmt.Errorf("invalid claims")
 	}, nil
 }
+
+// claimScopes returns the scopes from the scope claim (space separated)
+// or the scp claim (list) used by some providers
+func claimScopes(claims jwt.MapClaims) []string {
+	if v, ok := claims["scope"].(string); ok {
+		return strings.Fields(v)
+	}
+
+	var scopes []string
+	if v, ok := claims["scp"].([]interface{}); ok {
+		for _, s := range v {
+			if s1, ok := s.(string); ok {
+				scopes = append(scopes, s1)
+			}
+		}
+	}
+	return scopes
+}
+
+// tokenFromRequest returns the token from the cookie or the bearer
+// token from the authorization header
+func tokenFromRequest(r *http.Request, cookie string) string {
+	if cookie != "" {
+		if ck, err := r.Cookie(cookie); err == nil && len(ck.Value) != 0 {
+			return ck.Value
+		}
+	}
+
+	if ah := r.Header.Get(authHeader); len(ah) > 10 {
+		return ah[7:]
+	}
+	return ""
+}
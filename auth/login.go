@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+)
+
+// LoginConfig is the config for the built-in login, a one-time code is sent
+// to the user and exchanged for a short-lived jwt token signed with the
+// jwt secret. The codes are stored hashed in a database table with the
+// following columns:
+//
+//	CREATE TABLE login_codes (
+//		login varchar(255) NOT NULL PRIMARY KEY,
+//		code_hash varchar(64) NOT NULL,
+//		attempts integer NOT NULL DEFAULT 0,
+//		expires_at timestamp NOT NULL
+//	);
+type LoginConfig struct {
+	// Enable the login endpoints of the service
+	Enable bool
+
+	// Table is the database table that holds the hashed login codes
+	Table string `jsonschema:"title=Login Codes Table,default=login_codes"`
+
+	// UserQuery returns the user ID for a login, when not set the login
+	// itself is used as the user ID. Example: SELECT id FROM users WHERE email = $1
+	UserQuery string `mapstructure:"user_query" jsonschema:"title=User ID Query"`
+
+	// CodeTTL is how long a login code can be used
+	CodeTTL time.Duration `mapstructure:"code_ttl" jsonschema:"title=Login Code TTL,default=10m"`
+
+	// TokenTTL is how long the issued jwt token is valid
+	TokenTTL time.Duration `mapstructure:"token_ttl" jsonschema:"title=Token TTL,default=1h"`
+
+	// MaxAttempts is the number of codes that can be tried before the login is
+	// locked, the lock is removed once the last code sent has expired
+	MaxAttempts int `mapstructure:"max_attempts" jsonschema:"title=Maximum Attempts,default=5"`
+}
+
+// CodeSender delivers the login codes to the users (eg. by email or sms)
+type CodeSender interface {
+	SendCode(c context.Context, login, code string) error
+}
+
+var (
+	ErrLoginCode    = errors.New("invalid or expired login code")
+	ErrLoginUnknown = errors.New("unknown login")
+)
+
+// LoginStore creates and verifies the one-time login codes
+type LoginStore struct {
+	db          *sql.DB
+	dbType      string
+	table       string
+	userQuery   string
+	codeTTL     time.Duration
+	maxAttempts int
+}
+
+// NewLoginStore returns a new login store that uses the provided database
+func NewLoginStore(db *sql.DB, dbType string, conf LoginConfig) (*LoginStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("no database defined for the login codes")
+	}
+
+	table := conf.Table
+	if table == "" {
+		table = "login_codes"
+	}
+	if !tableRe.MatchString(table) {
+		return nil, fmt.Errorf("invalid login codes table: %s", table)
+	}
+
+	ttl := conf.CodeTTL
+	if ttl == 0 {
+		ttl = 10 * time.Minute
+	}
+
+	attempts := conf.MaxAttempts
+	if attempts == 0 {
+		attempts = 5
+	}
+
+	s := &LoginStore{
+		db:          db,
+		dbType:      dbType,
+		table:       table,
+		codeTTL:     ttl,
+		maxAttempts: attempts,
+	}
+	if conf.UserQuery != "" {
+		s.userQuery = s.query(conf.UserQuery)
+	}
+	return s, nil
+}
+
+// CreateCode returns a new login code, it replaces any earlier code of the
+// login. The wrong attempts are kept until the earlier code has expired so
+// that asking for a new code does not reset them.
+func (s *LoginStore) CreateCode(c context.Context, login string) (string, error) {
+	login = normLogin(login)
+	if login == "" {
+		return "", ErrLoginUnknown
+	}
+
+	if _, err := s.userID(c, login); err != nil {
+		return "", err
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	hash := hashLoginCode(login, code)
+
+	now := time.Now().UTC()
+	exp := now.Add(s.codeTTL)
+
+	// attempts is set before expires_at since mysql uses the new values
+	// of the columns set earlier in the statement
+	q := s.query(`UPDATE ` + s.table + ` SET code_hash = $1,` +
+		` attempts = CASE WHEN expires_at > $2 THEN attempts ELSE 0 END,` +
+		` expires_at = $3 WHERE login = $4`)
+
+	res, err := s.db.ExecContext(c, q, hash, now, exp, login)
+	if err != nil {
+		return "", err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 0 {
+		return code, err
+	}
+
+	q = s.query(`INSERT INTO ` + s.table +
+		` (login, code_hash, attempts, expires_at) VALUES ($1, $2, 0, $3)`)
+
+	if _, err = s.db.ExecContext(c, q, login, hash, exp); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// VerifyCode checks the login code and returns the user ID, a code can
+// only be used once
+func (s *LoginStore) VerifyCode(c context.Context, login, code string) (string, error) {
+	login = normLogin(login)
+
+	// the attempt is counted before the code is checked so that
+	// concurrent requests cannot get past the max attempts
+	q := s.query(`UPDATE ` + s.table + ` SET attempts = attempts + 1` +
+		` WHERE login = $1 AND attempts < $2 AND expires_at > $3`)
+
+	res, err := s.db.ExecContext(c, q, login, s.maxAttempts, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return "", ErrLoginCode
+	}
+
+	var hash string
+
+	q = s.query(`SELECT code_hash FROM ` + s.table + ` WHERE login = $1`)
+
+	err = s.db.QueryRowContext(c, q, login).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", ErrLoginCode
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashLoginCode(login, code))) != 1 {
+		return "", ErrLoginCode
+	}
+
+	// the code is removed before use so it cannot be used twice
+	q = s.query(`DELETE FROM ` + s.table + ` WHERE login = $1 AND code_hash = $2`)
+
+	res, err = s.db.ExecContext(c, q, login, hash)
+	if err != nil {
+		return "", err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return "", ErrLoginCode
+	}
+	return s.userID(c, login)
+}
+
+// userID returns the user ID of the login
+func (s *LoginStore) userID(c context.Context, login string) (string, error) {
+	if s.userQuery == "" {
+		return login, nil
+	}
+
+	var id string
+	err := s.db.QueryRowContext(c, s.userQuery, login).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", ErrLoginUnknown
+	}
+	return id, err
+}
+
+// query replaces the $n params with ? for mysql
+func (s *LoginStore) query(q string) string {
+	if s.dbType == "mysql" {
+		return paramRe.ReplaceAllString(q, "?")
+	}
+	return q
+}
+
+// IssueToken returns a jwt token for the user signed with the jwt secret,
+// the token can be verified by the jwt auth using the same config
+func IssueToken(conf JWTConfig, userID string, ttl time.Duration) (string, error) {
+	if conf.Secret == "" {
+		return "", fmt.Errorf("no jwt.secret defined")
+	}
+	if conf.PubKey != "" {
+		return "", fmt.Errorf("jwt.public_key cannot be used to verify issued tokens")
+	}
+
+	now := time.Now()
+	claims := jwt.StandardClaims{
+		Subject:   userID,
+		Issuer:    conf.Issuer,
+		Audience:  conf.Audience,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(conf.Secret))
+}
+
+func normLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+func hashLoginCode(login, code string) string {
+	h := sha256.Sum256([]byte(login + ":" + code))
+	return hex.EncodeToString(h[:])
+}
@@ -1,14 +0,0 @@
-// +build !magiclink
-
-package auth
-
-/*
-import (
-	"errors"
-	"net/http"
-)
-
-func MagicLinkHandler(ac *Auth, next http.Handler) (handlerFunc, error) {
-	return nil, errors.New("rebuild with the 'magiclink' tag")
-}
-*/
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dosco/graphjin/core/v3"
+)
+
+// MTLSConfig is the config for the mutual TLS client certificate
+// authentication. The fields can be one of cn (subject common name),
+// ou (subject organizational unit), o (subject organization),
+// san_dns, san_email, san_uri or an OID (eg. 1.3.6.1.4.1.99999.1) of
+// a subject attribute or a certificate extension.
+type MTLSConfig struct {
+	// UserIDField is the certificate field used as the user ID
+	UserIDField string `mapstructure:"user_id_field" jsonschema:"title=User ID Field,default=cn"`
+
+	// RoleField is the certificate field used as the user role
+	RoleField string `mapstructure:"role_field" jsonschema:"title=User Role Field"`
+}
+
+// MTLSHandler returns a handler that authenticates using the client certificate
+// verified by the TLS listener of the service
+func MTLSHandler(ac Auth) (HandlerFunc, error) {
+	userIDField := ac.MTLS.UserIDField
+	if userIDField == "" {
+		userIDField = "cn"
+	}
+
+	if err := validCertField(userIDField); err != nil {
+		return nil, err
+	}
+
+	roleField := ac.MTLS.RoleField
+	if roleField != "" {
+		if err := validCertField(roleField); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(_ http.ResponseWriter, r *http.Request) (context.Context, error) {
+		// only certificates verified against the client ca are used
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return nil, nil
+		}
+		cert := r.TLS.VerifiedChains[0][0]
+
+		userID := certField(cert, userIDField)
+		if userID == "" {
+			return nil, fmt.Errorf("client certificate: no %s found", userIDField)
+		}
+		c := context.WithValue(r.Context(), core.UserIDKey, userID)
+
+		if roleField != "" {
+			if v := certField(cert, roleField); v != "" {
+				c = context.WithValue(c, core.UserRoleKey, v)
+			}
+		}
+		return c, nil
+	}, nil
+}
+
+// validCertField checks if the certificate field is supported
+func validCertField(field string) error {
+	switch field {
+	case "cn", "ou", "o", "san_dns", "san_email", "san_uri":
+		return nil
+	}
+	if _, err := parseOID(field); err != nil {
+		return fmt.Errorf("invalid client certificate field: %s", field)
+	}
+	return nil
+}
+
+// certField returns the value of a field of the certificate
+func certField(cert *x509.Certificate, field string) string {
+	switch field {
+	case "cn":
+		return cert.Subject.CommonName
+	case "ou":
+		return first(cert.Subject.OrganizationalUnit)
+	case "o":
+		return first(cert.Subject.Organization)
+	case "san_dns":
+		return first(cert.DNSNames)
+	case "san_email":
+		return first(cert.EmailAddresses)
+	case "san_uri":
+		if len(cert.URIs) != 0 {
+			return cert.URIs[0].String()
+		}
+		return ""
+	}
+
+	oid, err := parseOID(field)
+	if err != nil {
+		return ""
+	}
+
+	for _, v := range cert.Subject.Names {
+		if v.Type.Equal(oid) {
+			if s, ok := v.Value.(string); ok {
+				return s
+			}
+		}
+	}
+
+	// extension values are expected to be an asn.1 encoded string
+	for _, v := range cert.Extensions {
+		var s string
+		if v.Id.Equal(oid) {
+			if _, err := asn1.Unmarshal(v.Value, &s); err == nil {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// parseOID parses an OID in the dotted format
+func parseOID(v string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(v, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid oid: %s", v)
+	}
+
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid oid: %s", v)
+		}
+		oid[i] = n
+	}
+	return oid, nil
+}
+
+func first(v []string) string {
+	if len(v) != 0 {
+		return v[0]
+	}
+	return ""
+}
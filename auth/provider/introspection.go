@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dosco/graphjin/core/v3"
+)
+
+// IntrospectionConfig struct contains the OAuth2 token introspection (RFC 7662)
+// related config values used by the GraphJin service
+type IntrospectionConfig struct {
+	// URL of the token introspection endpoint
+	// Example: https://YOUR_DOMAIN/oauth2/introspect
+	URL string `jsonschema:"title=Introspection Endpoint URL,example=https://YOUR_DOMAIN/oauth2/introspect"`
+
+	// Client ID used to authenticate with the introspection endpoint
+	ClientID string `mapstructure:"client_id" jsonschema:"title=Client ID"`
+
+	// Client secret used to authenticate with the introspection endpoint
+	ClientSecret string `mapstructure:"client_secret" jsonschema:"title=Client Secret"`
+
+	// Field of the introspection response that holds the user ID
+	UserIDField string `mapstructure:"user_id_field" jsonschema:"title=User ID Field,default=sub"`
+
+	// Field of the introspection response that holds the user role
+	RoleField string `mapstructure:"role_field" jsonschema:"title=User Role Field"`
+
+	// CacheTTL is how long inactive tokens and tokens without an
+	// expiry are cached, active tokens are cached until they expire
+	CacheTTL time.Duration `mapstructure:"cache_ttl" jsonschema:"title=Introspection Cache TTL,default=1m"`
+}
+
+// Introspection is the response of the token introspection endpoint
+type Introspection map[string]interface{}
+
+// Active returns true if the token is active
+func (in Introspection) Active() bool {
+	v, _ := in["active"].(bool)
+	return v
+}
+
+// IntrospectionProvider validates opaque access tokens using an
+// OAuth2 token introspection endpoint
+type IntrospectionProvider struct {
+	conf   IntrospectionConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[[32]byte]introspectionEntry
+	swept time.Time
+}
+
+type introspectionEntry struct {
+	in  Introspection
+	exp time.Time
+}
+
+// NewIntrospectionProvider creates a new OAuth2 token introspection provider
+func NewIntrospectionProvider(config IntrospectionConfig) (*IntrospectionProvider, error) {
+	if config.URL == "" {
+		return nil, errors.New("no introspection url defined")
+	}
+	if _, err := url.Parse(config.URL); err != nil {
+		return nil, fmt.Errorf("invalid introspection url: %w", err)
+	}
+
+	if config.UserIDField == "" {
+		config.UserIDField = "sub"
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = time.Minute
+	}
+
+	return &IntrospectionProvider{
+		conf:   config,
+		client: &http.Client{Timeout: 10 * time.Second},
+		cache:  make(map[[32]byte]introspectionEntry),
+	}, nil
+}
+
+// Introspect returns the introspection response for a token, the responses
+// are cached so the endpoint is only called once per token
+func (p *IntrospectionProvider) Introspect(c context.Context, token string) (Introspection, error) {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	p.mu.Lock()
+	e, ok := p.cache[key]
+	p.mu.Unlock()
+
+	if ok && now.Before(e.exp) {
+		return e.in, nil
+	}
+
+	in, err := p.introspect(c, token)
+	if err != nil {
+		return nil, err
+	}
+
+	e = introspectionEntry{in: in, exp: now.Add(p.conf.CacheTTL)}
+	if in.Active() {
+		if v, ok := in["exp"].(float64); ok {
+			e.exp = time.Unix(int64(v), 0)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// remove the expired tokens from the cache
+	if now.Sub(p.swept) > time.Minute {
+		for k, v := range p.cache {
+			if !now.Before(v.exp) {
+				delete(p.cache, k)
+			}
+		}
+		p.swept = now
+	}
+	p.cache[key] = e
+	return in, nil
+}
+
+// introspect calls the introspection endpoint
+func (p *IntrospectionProvider) introspect(c context.Context, token string) (Introspection, error) {
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", "access_token")
+
+	req, err := http.NewRequestWithContext(c, http.MethodPost, p.conf.URL,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if p.conf.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(p.conf.ClientID), url.QueryEscape(p.conf.ClientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection failed: %s", res.Status)
+	}
+
+	var in Introspection
+	if err := json.NewDecoder(res.Body).Decode(&in); err != nil {
+		return nil, fmt.Errorf("introspection failed: %w", err)
+	}
+	return in, nil
+}
+
+// SetContextValues sets the user ID, role, scopes and claims in the context
+func (p *IntrospectionProvider) SetContextValues(ctx context.Context, in Introspection) (context.Context, error) {
+	if !in.Active() {
+		return ctx, errors.New("token not active")
+	}
+
+	switch v := in[p.conf.UserIDField].(type) {
+	case string:
+		if v == "" {
+			return ctx, fmt.Errorf("%s field not found", p.conf.UserIDField)
+		}
+		ctx = context.WithValue(ctx, core.UserIDKey, v)
+	case float64:
+		ctx = context.WithValue(ctx, core.UserIDKey, fmt.Sprintf("%.0f", v))
+	default:
+		return ctx, fmt.Errorf("%s field not found", p.conf.UserIDField)
+	}
+
+	if p.conf.RoleField != "" {
+		if v, ok := in[p.conf.RoleField].(string); ok && v != "" {
+			ctx = context.WithValue(ctx, core.UserRoleKey, v)
+		}
+	}
+
+	if v, ok := in["scope"].(string); ok {
+		if scopes := strings.Fields(v); len(scopes) != 0 {
+			ctx = context.WithValue(ctx, core.UserScopesKey, scopes)
+		}
+	}
+	ctx = context.WithValue(ctx, core.UserClaimsKey, map[string]interface{}(in))
+	return ctx, nil
+}
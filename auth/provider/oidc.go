@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dosco/graphjin/core/v3"
+	jwt "github.com/golang-jwt/jwt"
+)
+
+const oidcDiscoveryPath = "/.well-known/openid-configuration"
+
+// OIDCIssuer is a trusted OpenID Connect issuer
+type OIDCIssuer struct {
+	// Name of the issuer it's set as the provider of the user. Eg. google
+	Name string
+
+	// Issuer value that the JWT token needs to match, the discovery
+	// document is fetched from <issuer>/.well-known/openid-configuration
+	// Example: https://accounts.google.com
+	Issuer string `jsonschema:"title=Issuer,example=https://accounts.google.com"`
+
+	// Audience value (client ID) that the JWT token needs to match, it's required
+	Audience string `jsonschema:"title=Match Audience Value"`
+}
+
+type OIDCProvider struct {
+	issuers     map[string]*oidcIssuer
+	nonceCookie string
+	refresh     int
+	minRefresh  int
+}
+
+type oidcIssuer struct {
+	OIDCIssuer
+
+	mu         sync.Mutex
+	cache      *keychainCache
+	discovered time.Time
+}
+
+// NewOIDCProvider creates a new OpenID Connect JWT provider, the JWKS
+// endpoint of every issuer is discovered when it's first used
+func NewOIDCProvider(config JWTConfig) (*OIDCProvider, error) {
+	p := &OIDCProvider{
+		issuers:     make(map[string]*oidcIssuer),
+		nonceCookie: config.NonceCookie,
+		refresh:     config.JWKSRefresh,
+		minRefresh:  config.JWKSMinRefresh,
+	}
+
+	issuers := config.Issuers
+	if config.Issuer != "" {
+		issuers = append([]OIDCIssuer{{Issuer: config.Issuer, Audience: config.Audience}}, issuers...)
+	}
+
+	for _, v := range issuers {
+		if v.Issuer == "" {
+			return nil, errors.New("undefined issuer")
+		}
+		if _, ok := p.issuers[v.Issuer]; ok {
+			return nil, fmt.Errorf("duplicate issuer: %s", v.Issuer)
+		}
+		// tokens issued to other clients of the issuer must not be accepted
+		if v.Audience == "" {
+			return nil, fmt.Errorf("undefined audience for issuer: %s", v.Issuer)
+		}
+		p.issuers[v.Issuer] = &oidcIssuer{OIDCIssuer: v}
+	}
+
+	if len(p.issuers) == 0 {
+		return nil, errors.New("undefined issuer")
+	}
+	return p, nil
+}
+
+// KeyFunc returns a function that returns the key used to verify the JWT token
+func (p *OIDCProvider) KeyFunc() jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if token == nil {
+			return nil, errors.New("null token")
+		}
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil, errors.New("undefined claims")
+		}
+		is, err := p.issuer(claims)
+		if err != nil {
+			return nil, err
+		}
+		kid, found := token.Header["kid"].(string)
+		if !found {
+			return nil, errors.New("kid not found")
+		}
+		cache, err := is.keychain(p.refresh, p.minRefresh)
+		if err != nil {
+			return nil, err
+		}
+		return cache.getKey(kid)
+	}
+}
+
+// VerifyAudience checks if the audience claim is valid, when the token has
+// multiple audiences or an authorized party claim (azp) it must match the
+// audience of the issuer
+func (p *OIDCProvider) VerifyAudience(claims jwt.MapClaims) bool {
+	is, err := p.issuer(claims)
+	if err != nil {
+		return false
+	}
+	aud := is.Audience
+	if aud == "" || !claims.VerifyAudience(aud, true) {
+		return false
+	}
+
+	azp, found := claims["azp"].(string)
+	if !found && !multipleAudiences(claims) {
+		return true
+	}
+	return azp == aud
+}
+
+// VerifyIssuer checks if the issuer claim is one of the trusted issuers
+func (p *OIDCProvider) VerifyIssuer(claims jwt.MapClaims) bool {
+	_, err := p.issuer(claims)
+	return err == nil
+}
+
+// VerifyRequest checks if the nonce claim matches the nonce cookie
+func (p *OIDCProvider) VerifyRequest(r *http.Request, claims jwt.MapClaims) error {
+	if p.nonceCookie == "" {
+		return nil
+	}
+	ck, err := r.Cookie(p.nonceCookie)
+	if err != nil || ck.Value == "" {
+		return errors.New("nonce cookie not found")
+	}
+	nonce, _ := claims["nonce"].(string)
+	if subtle.ConstantTimeCompare([]byte(nonce), []byte(ck.Value)) != 1 {
+		return errors.New("invalid nonce claim")
+	}
+	return nil
+}
+
+// SetContextValues sets the user ID and provider in the context
+func (p *OIDCProvider) SetContextValues(ctx context.Context, claims jwt.MapClaims) (context.Context, error) {
+	is, err := p.issuer(claims)
+	if err != nil {
+		return ctx, err
+	}
+	sub, found := claims["sub"].(string)
+	if !found || sub == "" {
+		return ctx, errors.New("subject claim not found")
+	}
+	ctx = context.WithValue(ctx, core.UserIDKey, sub)
+
+	if is.Name != "" {
+		ctx = context.WithValue(ctx, core.UserIDProviderKey, is.Name)
+	}
+	return ctx, nil
+}
+
+// issuer returns the trusted issuer of the token
+func (p *OIDCProvider) issuer(claims jwt.MapClaims) (*oidcIssuer, error) {
+	if claims == nil {
+		return nil, errors.New("undefined claims")
+	}
+	iss, _ := claims["iss"].(string)
+	if is, ok := p.issuers[iss]; ok {
+		return is, nil
+	}
+	return nil, fmt.Errorf("untrusted issuer: %s", iss)
+}
+
+// keychain returns the keys of the issuer, the discovery document is
+// fetched once and retried at most once per minute when it fails
+func (is *oidcIssuer) keychain(refresh, minRefresh int) (*keychainCache, error) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if is.cache != nil {
+		return is.cache, nil
+	}
+	if time.Since(is.discovered) < time.Minute {
+		return nil, fmt.Errorf("oidc discovery failed: %s", is.Issuer)
+	}
+	is.discovered = time.Now()
+
+	jwksURL, err := discoverJWKS(is.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	is.cache = newKeychainCache(jwksURL, refresh, minRefresh)
+	return is.cache, nil
+}
+
+// discoverJWKS fetches the discovery document of the issuer
+// and returns the url of its JWKS endpoint
+func discoverJWKS(issuer string) (string, error) {
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	res, err := client.Get(strings.TrimSuffix(issuer, "/") + oidcDiscoveryPath)
+	if err != nil {
+		return "", fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc discovery failed: %s: %s", issuer, res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("oidc discovery failed: %w", err)
+	}
+
+	// the issuer in the discovery document must match the one
+	// that was used to fetch it
+	if doc.Issuer != issuer {
+		return "", fmt.Errorf("oidc discovery failed: issuer mismatch: %s", doc.Issuer)
+	}
+	if doc.JWKSURI == "" {
+		return "", fmt.Errorf("oidc discovery failed: no jwks_uri: %s", issuer)
+	}
+	return doc.JWKSURI, nil
+}
+
+// multipleAudiences returns true if the token has more than one audience
+func multipleAudiences(claims jwt.MapClaims) bool {
+	v, ok := claims["aud"].([]interface{})
+	return ok && len(v) > 1
+}
@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"errors"
+	"net/http"
 
 	jwt "github.com/golang-jwt/jwt"
 )
@@ -10,8 +11,8 @@ import (
 // JWTConfig struct contains JWT authentication related config values used by
 // the GraphJin service
 type JWTConfig struct {
-	// Provider can be one of auth0, firebase, jwks or other
-	Provider string `jsonschema:"title=JWT Provider,enum=auth0,enum=firebase,enum=jwks,enum=other"`
+	// Provider can be one of auth0, firebase, jwks, oidc or other
+	Provider string `jsonschema:"title=JWT Provider,enum=auth0,enum=firebase,enum=jwks,enum=oidc,enum=other"`
 
 	// The secret key used for signing and encrypting the JWT token
 	Secret string `jsonschema:"title=JWT Secret Key"`
@@ -40,6 +41,14 @@ type JWTConfig struct {
 	// JWKSMinRefresh sets in minutes fallback value when tokens are refreshed, default
 	// to 60 minutes
 	JWKSMinRefresh int `mapstructure:"jwks_min_refresh" jsonschema:"title=JWKS Minimum Refresh Timeout (minutes)"`
+
+	// Issuers is a list of trusted OpenID Connect issuers used by the oidc
+	// provider along with the issuer and audience values if set
+	Issuers []OIDCIssuer `jsonschema:"title=Trusted OpenID Connect Issuers"`
+
+	// NonceCookie is the name of the cookie that holds the nonce used to
+	// request the token, the oidc provider checks it against the nonce claim
+	NonceCookie string `mapstructure:"nonce_cookie" jsonschema:"title=Nonce Cookie Name"`
 }
 
 // JWTProvider is the interface to define providers for doing JWT
@@ -51,6 +60,12 @@ type JWTProvider interface {
 	SetContextValues(context.Context, jwt.MapClaims) (context.Context, error)
 }
 
+// RequestVerifier is implemented by the providers that need to check the
+// claims against the request
+type RequestVerifier interface {
+	VerifyRequest(*http.Request, jwt.MapClaims) error
+}
+
 // NewProvider creates a new JWT provider based on the config values
 func NewProvider(config JWTConfig) (JWTProvider, error) {
 	switch config.Provider {
@@ -60,6 +75,8 @@ func NewProvider(config JWTConfig) (JWTProvider, error) {
 		return NewFirebaseProvider(config)
 	case "jwks":
 		return NewJWKSProvider(config)
+	case "oidc":
+		return NewOIDCProvider(config)
 	default:
 		return NewGenericProvider(config)
 	}
@@ -2,6 +2,9 @@ package auth
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
@@ -16,9 +19,17 @@ import (
 )
 
 // RailsHandler returns a handler that authenticates using a Rails session cookie
-func RailsHandler(ac Auth) (HandlerFunc, error) {
+func RailsHandler(ac Auth, opts ...HandlerOption) (HandlerFunc, error) {
 	ru := ac.Rails.URL
 
+	if ac.Rails.SessionTable != "" {
+		var o handlerOptions
+		for _, opt := range opts {
+			opt(&o)
+		}
+		return RailsDBHandler(ac, o.db, o.dbType)
+	}
+
 	if strings.HasPrefix(ru, "memcache:") {
 		return RailsMemcacheHandler(ac)
 	}
@@ -141,6 +152,82 @@ func RailsMemcacheHandler(ac Auth) (HandlerFunc, error) {
 	}, nil
 }
 
+// RailsDBHandler returns a handler that authenticates using a Rails session cookie
+// with the sessions stored in the database by the activerecord-session_store gem
+func RailsDBHandler(ac Auth, db *sql.DB, dbType string) (HandlerFunc, error) {
+	cookie := ac.Cookie
+
+	if len(cookie) == 0 {
+		return nil, fmt.Errorf("no auth.cookie defined")
+	}
+
+	if db == nil {
+		return nil, fmt.Errorf("no database defined for the rails session table")
+	}
+
+	table := ac.Rails.SessionTable
+	if !tableRe.MatchString(table) {
+		return nil, fmt.Errorf("invalid rails session table: %s", table)
+	}
+
+	// newer versions of the gem store a hash of the session id
+	// the plain session id is used by older versions
+	q := `SELECT data FROM ` + table + ` WHERE session_id IN ($1, $2)`
+
+	// sessions are only valid for as long as they are kept active
+	maxAge := ac.Rails.SessionMaxAge
+	if maxAge > 0 {
+		q += ` AND updated_at > $3`
+	}
+
+	if dbType == "mysql" {
+		q = paramRe.ReplaceAllString(q, "?")
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+		ck, err := r.Cookie(cookie)
+		if err == http.ErrNoCookie {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		sid, err := url.QueryUnescape(ck.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		args := []interface{}{railsPrivateID(sid), sid}
+		if maxAge > 0 {
+			args = append(args, time.Now().Add(-maxAge))
+		}
+
+		var data string
+		err = db.QueryRowContext(r.Context(), q, args...).Scan(&data)
+		if err == sql.ErrNoRows {
+			return nil, errors.New("rails session not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		userID, err := rails.ParseSession(data)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx := context.WithValue(r.Context(), core.UserIDKey, userID)
+		return ctx, nil
+	}, nil
+}
+
+// railsPrivateID returns the id a session is stored with by Rack
+func railsPrivateID(sid string) string {
+	h := sha256.Sum256([]byte(sid))
+	return "2::" + hex.EncodeToString(h[:])
+}
+
 // RailsCookieHandler returns a handler that authenticates using a Rails session cookie
 func RailsCookieHandler(ac Auth) (HandlerFunc, error) {
 	cookie := ac.Cookie
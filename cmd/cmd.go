@@ -48,6 +48,10 @@ func Cmd() {
 	rootCmd.AddCommand(initCmd())
 	rootCmd.AddCommand(deployCmd())
 	rootCmd.AddCommand(dbCmd())
+	rootCmd.AddCommand(openAPICmd())
+	rootCmd.AddCommand(protoCmd())
+	rootCmd.AddCommand(persistedCmd())
+	rootCmd.AddCommand(apiKeyCmd())
 
 	if v := cmdSecrets(); v != nil {
 		rootCmd.AddCommand(v)
@@ -97,6 +101,19 @@ func initDB(openDB bool) {
 	dbOpened = openDB
 }
 
+// initGraphJin is a helper function to initialize GraphJin
+// with the queries from the config path
+func initGraphJin() *core.GraphJin {
+	setup(cpath)
+	initDB(true)
+
+	gj, err := core.NewGraphJin(&conf.Core, db, core.OptionSetFS(core.NewOsFS(cpath)))
+	if err != nil {
+		log.Fatalf("Failed to initialize GraphJin: %s", err)
+	}
+	return gj
+}
+
 // newLogger creates a new logger
 func newLogger(json bool) *zap.Logger {
 	econf := zapcore.EncoderConfig{
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dosco/graphjin/auth/v3"
+	"github.com/dosco/graphjin/serv/v3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keyUserID  string
+	keyRole    string
+	keyScopes  string
+	keyExpires time.Duration
+)
+
+// apiKeyCmd creates the api key command
+func apiKeyCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "apikey",
+		Short: "Manage the api keys used by the apikey auth",
+	}
+	c.PersistentFlags().StringVar(&host, "host", "", "URL of the GraphJin service")
+	c.PersistentFlags().StringVar(&secret, "secret", "", "Set the admin auth secret key")
+
+	c1 := &cobra.Command{
+		Use:   "create",
+		Short: "Create a new api key for a user",
+		Run:   cmdAPIKeyCreate,
+	}
+	c1.Flags().StringVar(&keyUserID, "user-id", "", "ID of the user the key belongs to")
+	c1.Flags().StringVar(&keyRole, "role", "", "Role of the user")
+	c1.Flags().StringVar(&keyScopes, "scopes", "", "Space separated list of scopes")
+	c1.Flags().DurationVar(&keyExpires, "expires", 0, "Expire the key after a duration (ex. 720h)")
+	c.AddCommand(c1)
+
+	c2 := &cobra.Command{
+		Use:   "rotate <id>",
+		Short: "Replace an api key with a new one",
+		Args:  cobra.ExactArgs(1),
+		Run:   cmdAPIKeyRotate,
+	}
+	c.AddCommand(c2)
+
+	c3 := &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revoke an api key",
+		Args:  cobra.ExactArgs(1),
+		Run:   cmdAPIKeyRevoke,
+	}
+	c.AddCommand(c3)
+
+	return c
+}
+
+// cmdAPIKeyCreate creates a new api key
+func cmdAPIKeyCreate(cmd *cobra.Command, args []string) {
+	if keyUserID == "" {
+		log.Fatalf("--user-id is a required argument")
+	}
+
+	k := auth.APIKey{
+		UserID: keyUserID,
+		Role:   keyRole,
+		Scopes: strings.Fields(keyScopes),
+	}
+	if keyExpires != 0 {
+		exp := time.Now().Add(keyExpires)
+		k.ExpiresAt = &exp
+	}
+
+	res, err := newAPIKeyClient().CreateAPIKey(k)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("API key created: id: %s, key: %s", res.ID, res.Key)
+}
+
+// cmdAPIKeyRotate replaces an api key with a new one
+func cmdAPIKeyRotate(cmd *cobra.Command, args []string) {
+	res, err := newAPIKeyClient().RotateAPIKey(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("API key rotated: id: %s, key: %s", res.ID, res.Key)
+}
+
+// cmdAPIKeyRevoke revokes an api key
+func cmdAPIKeyRevoke(cmd *cobra.Command, args []string) {
+	res, err := newAPIKeyClient().RevokeAPIKey(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("API key revoked: id: %s", res.ID)
+}
+
+// newAPIKeyClient returns an admin client for the api key commands
+func newAPIKeyClient() *serv.Client {
+	if host == "" {
+		log.Fatalf("--host is a required argument")
+	}
+
+	if secret == "" {
+		log.Fatalf("--secret is a required argument")
+	}
+
+	return serv.NewAdminClient(host, secret)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/dosco/graphjin/core/v3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	openAPIRole string
+	openAPIOut  string
+)
+
+// openAPICmd creates the openapi command
+func openAPICmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "openapi",
+		Short: "Export the OpenAPI document for the REST endpoint",
+		Run:   cmdOpenAPI,
+	}
+	c.Flags().StringVar(&openAPIRole, "role", "user", "Role to generate the document for")
+	c.Flags().StringVarP(&openAPIOut, "output", "o", "", "File to write the document to (default stdout)")
+	return c
+}
+
+// cmdOpenAPI generates the OpenAPI document from the queries in the allow list
+func cmdOpenAPI(cmd *cobra.Command, args []string) {
+	gj := initGraphJin()
+
+	c := context.WithValue(context.Background(), core.UserRoleKey, openAPIRole)
+	doc, err := gj.OpenAPI(c, core.OpenAPIConfig{
+		Title:    conf.AppName,
+		BasePath: "/api/v1/rest",
+		Routes:   conf.OpenAPIRoutes(),
+	})
+	if err != nil {
+		log.Fatalf("Failed to generate OpenAPI document: %s", err)
+	}
+
+	var b bytes.Buffer
+	if err := json.Indent(&b, doc, "", "  "); err != nil {
+		log.Fatal(err)
+	}
+	b.WriteByte('\n')
+
+	if openAPIOut == "" {
+		_, _ = os.Stdout.Write(b.Bytes())
+		return
+	}
+
+	if err := os.WriteFile(openAPIOut, b.Bytes(), 0o644); err != nil {
+		log.Fatalf("Failed to write OpenAPI document: %s", err)
+	}
+	log.Infof("OpenAPI document written to %s", openAPIOut)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// persistedCmd creates the persisted queries command
+func persistedCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "persisted",
+		Short: "Manage persisted queries",
+	}
+
+	c1 := &cobra.Command{
+		Use:   "import <manifest.json>",
+		Short: "Import a persisted query manifest into the allow list",
+		Long: "This command adds the queries from an Apollo or Relay persisted query " +
+			"manifest to the allow list so they can be used by their hash",
+		Args: cobra.ExactArgs(1),
+		Run:  cmdPersistedImport,
+	}
+	c.AddCommand(c1)
+
+	return c
+}
+
+// cmdPersistedImport imports a persisted query manifest into the allow list
+func cmdPersistedImport(cmd *cobra.Command, args []string) {
+	b, err := os.ReadFile(args[0])
+	if err != nil {
+		log.Fatalf("Failed to read manifest: %s", err)
+	}
+
+	gj := initGraphJin()
+
+	names, err := gj.ImportPersistedQueries(b)
+	if err != nil {
+		log.Fatalf("Failed to import persisted queries: %s", err)
+	}
+
+	for _, v := range names {
+		log.Infof("Imported query: %s", v)
+	}
+	log.Infof("%d queries imported from %s", len(names), args[0])
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/dosco/graphjin/serv/v3"
+	"github.com/spf13/cobra"
+)
+
+var protoOut string
+
+// protoCmd creates the proto command
+func protoCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "proto",
+		Short: "Export the .proto file for the gRPC service",
+		Run:   cmdProto,
+	}
+	c.Flags().StringVarP(&protoOut, "output", "o", "", "File to write the .proto file to (default stdout)")
+	return c
+}
+
+// cmdProto generates the .proto file from the queries in the allow list
+func cmdProto(cmd *cobra.Command, args []string) {
+	gj := initGraphJin()
+
+	b, err := serv.ProtoFile(context.Background(), gj, conf.GRPC)
+	if err != nil {
+		log.Fatalf("Failed to generate .proto file: %s", err)
+	}
+
+	if protoOut == "" {
+		_, _ = os.Stdout.Write(b)
+		return
+	}
+
+	if err := os.WriteFile(protoOut, b, 0o644); err != nil {
+		log.Fatalf("Failed to write .proto file: %s", err)
+	}
+	log.Infof(".proto file written to %s", protoOut)
+}
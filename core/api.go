@@ -38,6 +38,12 @@ const (
 
 	// User role if pre-defined
 	UserRoleKey
+
+	// Scopes granted to the user ([]string)
+	UserScopesKey
+
+	// Claims of the user's auth token (map[string]interface{})
+	UserClaimsKey
 )
 
 const (
@@ -52,6 +58,7 @@ type graphjinEngine struct {
 	log                   *_log.Logger
 	fs                    FS
 	trace                 Tracer
+	policy                Policy
 	dbtype                string
 	dbinfo                *sdata.DBInfo
 	schema                *sdata.DBSchema
@@ -258,6 +265,8 @@ type Result struct {
 	Hash         [sha256.Size]byte `json:"-"`
 	Errors       []Error           `json:"errors,omitempty"`
 	Validation   []qcode.ValidErr  `json:"validation,omitempty"`
+	Incremental  []Incremental     `json:"incremental,omitempty"`
+	HasNext      *bool             `json:"hasNext,omitempty"`
 	// Extensions   *extensions     `json:"extensions,omitempty"`
 }
 
@@ -265,7 +274,9 @@ type Result struct {
 type RequestConfig struct {
 	ns *string
 
-	// APQKey is set when using GraphJin with automatic persisted queries
+	// APQKey is set when using GraphJin with automatic persisted queries, it's
+	// the hash of the query and is also used to find the queries imported
+	// from a persisted query manifest
 	APQKey string
 
 	// Pass additional variables complex variables such as functions that return string values.
@@ -301,16 +312,67 @@ func (g *GraphJin) GraphQL(c context.Context,
 	vars json.RawMessage,
 	rc *RequestConfig,
 ) (res *Result, err error) {
+	res, _, err = g.graphQL(c, query, vars, rc, false)
+	return
+}
+
+// GraphQLIncremental is similar to the GraphQL function except that the parts
+// of the query marked with the @defer or @stream directives are fetched separately
+// and delivered as incremental results on the returned channel. The channel is nil
+// when there are no deferred parts and is closed once all of them are delivered.
+// Database transactions are not supported.
+func (g *GraphJin) GraphQLIncremental(c context.Context,
+	query string,
+	vars json.RawMessage,
+	rc *RequestConfig,
+) (res *Result, next <-chan *Result, err error) {
+	return g.graphQL(c, query, vars, rc, true)
+}
+
+func (g *GraphJin) graphQL(c context.Context,
+	query string,
+	vars json.RawMessage,
+	rc *RequestConfig,
+	incremental bool,
+) (res *Result, next <-chan *Result, err error) {
 	gj := g.Load().(*graphjinEngine)
 
+	// the deferred parts are fetched after returning so they cannot use the transaction
+	if incremental && rc != nil && rc.Tx != nil {
+		err = errors.New("incremental: database transactions not supported")
+		return
+	}
+
 	c1, span := gj.spanStart(c, "GraphJin Query")
 	defer span.End()
 
 	var queryBytes []byte
-	var inCache bool
+	var inCache, persisted bool
+	var item allow.Item
 
-	// get query from apq cache if apq key exists
+	// get query from the persisted queries imported into the allow list
 	if rc != nil && rc.APQKey != "" {
+		item, err = gj.allowList.GetByHash(rc.APQKey)
+		if err != nil && err != allow.ErrUnknownPersistedQuery {
+			return
+		}
+		persisted = (err == nil)
+		err = nil
+	}
+
+	// in strict mode only persisted queries can be used
+	if !persisted && gj.prodSec && gj.conf.StrictPersistedQueries {
+		err = ErrPersistedQueryNotFound
+		res = &Result{Errors: newError(err)}
+		return
+	}
+
+	switch {
+	case persisted:
+		queryBytes, inCache = item.Query, true
+
+	// get query from apq cache if apq key exists
+	case rc != nil && rc.APQKey != "":
 		queryBytes, inCache = gj.cache.Get(APQ_PX + rc.APQKey)
 	}
 
@@ -319,17 +381,29 @@ func (g *GraphJin) GraphQL(c context.Context,
 		queryBytes = []byte(query)
 	}
 
+	// let the client know to send the query along with the apq key, the
+	// error is returned as part of the result as expected by apq clients
+	if len(queryBytes) == 0 && rc != nil && rc.APQKey != "" {
+		err = ErrPersistedQueryNotFound
+		res = &Result{Errors: newError(err)}
+		return
+	}
+
 	// fast extract name and query type from query
 	h, err := graph.FastParseBytes(queryBytes)
 	if err != nil {
 		return
 	}
 	r := gj.newGraphqlReq(rc, h.Operation, h.Name, queryBytes, vars)
+	r.incremental = incremental
 
 	// if production security enabled then get query and metadata
 	// from allow list
-	if gj.prodSec {
-		var item allow.Item
+	switch {
+	case persisted:
+		r.Set(item)
+
+	case gj.prodSec:
 		item, err = gj.allowList.GetByName(h.Name, true)
 		if err != nil {
 			err = fmt.Errorf("%w: %s", err, h.Name)
@@ -341,6 +415,7 @@ func (g *GraphJin) GraphQL(c context.Context,
 	// do the query
 	resp, err := gj.query(c1, r)
 	res = &resp.res
+	next = resp.next
 	if err != nil {
 		return
 	}
@@ -351,7 +426,7 @@ func (g *GraphJin) GraphQL(c context.Context,
 	}
 
 	// if not production then save to allow list
-	if !gj.prod && r.name != "IntrospectionQuery" {
+	if !gj.prod && !persisted && r.name != "IntrospectionQuery" {
 		if err = gj.saveToAllowList(resp.qc, resp.res.namespace); err != nil {
 			return
 		}
@@ -424,11 +499,14 @@ type GraphqlReq struct {
 	vars          json.RawMessage
 	aschema       map[string]json.RawMessage
 	requestconfig *RequestConfig
+	incremental   bool
+	export        Exporter
 }
 
 type GraphqlResponse struct {
-	res Result
-	qc  *qcode.QCode
+	res  Result
+	qc   *qcode.QCode
+	next <-chan *Result
 }
 
 // newGraphqlReq creates a new GraphQL request
@@ -517,6 +595,12 @@ func (gj *graphjinEngine) query(c context.Context, r GraphqlReq) (
 	if len(s.verrs) != 0 {
 		resp.res.Validation = s.verrs
 	}
+
+	if err == nil && len(s.parts) != 0 {
+		hasNext := true
+		resp.res.HasNext = &hasNext
+		resp.next = s.executeParts(c)
+	}
 	return
 }
 
@@ -18,6 +18,18 @@ type args struct {
 	cindx  int // index of cursor arg
 }
 
+// isContextVar returns true for the variables that are set from the request context
+func isContextVar(name string) bool {
+	switch name {
+	case "user_id", "userID", "userId",
+		"user_id_raw", "userIDRaw", "userIdRaw",
+		"user_id_provider", "userIDProvider", "userIdProvider",
+		"user_role", "userRole":
+		return true
+	}
+	return false
+}
+
 func (gj *graphjinEngine) argList(c context.Context,
 	md psql.Metadata,
 	fields map[string]json.RawMessage,
@@ -29,6 +41,11 @@ func (gj *graphjinEngine) argList(c context.Context,
 	vl := make([]interface{}, len(params))
 
 	for i, p := range params {
+		if p.HasValue {
+			vl[i] = p.Value
+			continue
+		}
+
 		switch p.Name {
 		case "user_id", "userID", "userId":
 			if v := c.Value(UserIDKey); v != nil {
@@ -40,7 +57,7 @@ func (gj *graphjinEngine) argList(c context.Context,
 				case float64:
 					vl[i] = int(v1)
 				default:
-					return ar, fmt.Errorf("%s must be an integer or a string: %T", p.Name, v)
+					return ar, varErrorf("%s must be an integer or a string: %T", p.Name, v)
 				}
 			} else {
 				return ar, argErr(p)
@@ -81,13 +98,16 @@ func (gj *graphjinEngine) argList(c context.Context,
 
 				switch {
 				case p.IsNotNull && varIsNull:
-					return ar, fmt.Errorf("variable '%s' cannot be null", p.Name)
+					return ar, varErrorf("variable '%s' cannot be null", p.Name)
 
 				case p.IsArray && v[0] != '[' && !varIsNull:
-					return ar, fmt.Errorf("variable '%s' should be an array of type '%s'", p.Name, p.Type)
+					return ar, varErrorf("variable '%s' should be an array of type '%s'", p.Name, p.Type)
 
 				case p.Type == "json" && v[0] != '[' && v[0] != '{' && !varIsNull:
-					return ar, fmt.Errorf("variable '%s' should be an array or object", p.Name)
+					return ar, varErrorf("variable '%s' should be an array or object", p.Name)
+
+				case p.Type == "vector" && v[0] != '[' && !varIsNull:
+					return ar, varErrorf("variable '%s' should be an array of numbers", p.Name)
 				}
 				vl[i] = parseVarVal(v)
 
@@ -142,5 +162,18 @@ func parseVarVal(v json.RawMessage) interface{} {
 }
 
 func argErr(p psql.Param) error {
-	return fmt.Errorf("required variable '%s' of type '%s' must be set", p.Name, p.Type)
+	return varErrorf("required variable '%s' of type '%s' must be set", p.Name, p.Type)
 }
+
+// varError is an error with a variable, it matches ErrInvalidVariable
+type varError struct {
+	msg string
+}
+
+func varErrorf(format string, a ...interface{}) error {
+	return varError{msg: fmt.Sprintf(format, a...)}
+}
+
+func (e varError) Error() string { return e.msg }
+
+func (e varError) Unwrap() error { return ErrInvalidVariable }
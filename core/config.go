@@ -99,6 +99,11 @@ type Config struct {
 	// When set to true it disables production security features like enforcing the allow list
 	DisableProdSecurity bool `mapstructure:"disable_production_security" json:"disable_production_security" yaml:"disable_production_security" jsonschema:"title=Disable Production Security"`
 
+	// When set to true in production only the persisted queries imported into the
+	// allow list can be used and requests must send the hash of the query instead of
+	// the query itself
+	StrictPersistedQueries bool `mapstructure:"strict_persisted_queries" json:"strict_persisted_queries" yaml:"strict_persisted_queries" jsonschema:"title=Strict Persisted Queries,default=false"`
+
 	// The filesystem to use for this instance of GraphJin
 	FS interface{} `mapstructure:"-" jsonschema:"-" json:"-"`
 }
@@ -154,6 +159,8 @@ type Query struct {
 	Columns          []string
 	DisableFunctions bool `mapstructure:"disable_functions" json:"disable_functions" yaml:"disable_functions"`
 	Block            bool
+	// Scopes the user needs to have to use this operation on the table, eg. read:products
+	Scopes []string
 }
 
 // Table configuration for inserting into a table with a role
@@ -162,6 +169,7 @@ type Insert struct {
 	Columns []string
 	Presets map[string]string
 	Block   bool
+	Scopes  []string
 }
 
 // Table configuration for updating a table with a role
@@ -170,6 +178,7 @@ type Update struct {
 	Columns []string
 	Presets map[string]string
 	Block   bool
+	Scopes  []string
 }
 
 // Table configuration for creating/updating (upsert) a table with a role
@@ -178,6 +187,7 @@ type Upsert struct {
 	Columns []string
 	Presets map[string]string
 	Block   bool
+	Scopes  []string
 }
 
 // Table configuration for deleting from a table with a role
@@ -185,6 +195,7 @@ type Delete struct {
 	Filters []string
 	Columns []string
 	Block   bool
+	Scopes  []string
 }
 
 // Resolver interface is used to create custom resolvers
@@ -20,6 +20,16 @@ import (
 var (
 	decPrefix   = []byte(`__gj/enc:`)
 	ErrNotFound = errors.New("not found in prepared statements")
+
+	// ErrUnknownQuery is returned when a named query is not in the allow list
+	ErrUnknownQuery = allow.ErrUnknownGraphQLQuery
+
+	// ErrInvalidVariable is returned when a variable is missing or has the wrong type
+	ErrInvalidVariable = errors.New("invalid variable")
+
+	// ErrPersistedQueryNotFound is returned when the hash of a persisted query is
+	// not known, the message is the one Apollo clients expect
+	ErrPersistedQueryNotFound = errors.New("PersistedQueryNotFound")
 )
 
 type OpType int
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dosco/graphjin/core/v3/internal/qcode"
+)
+
+// Exporter receives the rows of an export
+type Exporter interface {
+	// Columns is called once before the rows with the names of the fields
+	// selected by the query, the fields of nested objects are flattened
+	// into names like 'user.email'
+	Columns(cols []string) error
+
+	// Row is called with the json of each row, the row is only
+	// valid until the function returns
+	Row(row json.RawMessage) error
+}
+
+// ExportByName is similar to the GraphQLByName function except that the
+// named query must have a single root selector and instead of returning
+// the data, the exporter is called with the json of each row of the selector
+// as it is read from the database cursor. Since the rows are never collected
+// into a single result it can be used to export large tables. The returned
+// result has no data.
+func (g *GraphJin) ExportByName(c context.Context,
+	name string,
+	vars json.RawMessage,
+	rc *RequestConfig,
+	exp Exporter,
+) (res *Result, err error) {
+	gj := g.Load().(*graphjinEngine)
+
+	c1, span := gj.spanStart(c, "GraphJin Export")
+	defer span.End()
+
+	item, err := gj.allowList.GetByName(name, gj.prod)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", err, name)
+		return
+	}
+
+	r := gj.newGraphqlReq(rc, "", name, nil, vars)
+	r.Set(item)
+	r.export = exp
+
+	res, err = gj.queryWithResult(c1, r)
+	return
+}
+
+// executeExport executes the export statement and calls the exporter
+// with each of the rows
+func (s *gstate) executeExport(c context.Context, conn *sql.Conn) (err error) {
+	if err = s.validateAndUpdateVars(c); err != nil {
+		return
+	}
+
+	var args args
+	if args, err = s.argList(c); err != nil {
+		return
+	}
+
+	cs := s.cs
+
+	c1, span := s.gj.spanStart(c, "Execute Export")
+	defer span.End()
+
+	var rows *sql.Rows
+	if tx := s.tx(); tx != nil {
+		rows, err = tx.QueryContext(c1, cs.st.sql, args.values...)
+	} else {
+		err = retryOperation(c1, func() (err1 error) {
+			rows, err1 = conn.QueryContext(c1, cs.st.sql, args.values...)
+			return
+		})
+	}
+	if err != nil {
+		span.Error(err)
+		return
+	}
+	defer rows.Close()
+
+	if span.IsRecording() {
+		span.SetAttributesString(
+			StringAttr{"query.namespace", s.r.namespace},
+			StringAttr{"query.operation", cs.st.qc.Type.String()},
+			StringAttr{"query.name", cs.st.qc.Name},
+			StringAttr{"query.role", cs.st.role})
+	}
+
+	if err = s.r.export.Columns(exportColumns(cs.st.qc)); err != nil {
+		return
+	}
+
+	var row sql.RawBytes
+	for rows.Next() {
+		if err = rows.Scan(&row); err != nil {
+			span.Error(err)
+			return
+		}
+
+		data := []byte(row)
+		if bytes.Contains(data, s.gj.printFormat) {
+			h := sha256.Sum256(data)
+			data, err = encryptValues(data,
+				s.gj.printFormat, decPrefix, h[:], s.gj.encryptionKey)
+			if err != nil {
+				return
+			}
+		}
+
+		if err = s.r.export.Row(data); err != nil {
+			return
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		span.Error(err)
+	}
+	return
+}
+
+// exportColumns returns the flattened names of the fields selected
+// by the root selector
+func exportColumns(qc *qcode.QCode) (cols []string) {
+	for _, id := range qc.Roots {
+		sel := &qc.Selects[id]
+		if sel.SkipRender != qcode.SkipTypeDrop {
+			cols = appendColumns(cols, qc, sel, "")
+		}
+	}
+	return
+}
+
+func appendColumns(cols []string, qc *qcode.QCode, sel *qcode.Select, prefix string) []string {
+	for _, f := range sel.Fields {
+		if f.SkipRender != qcode.SkipTypeDrop {
+			cols = append(cols, prefix+f.FieldName)
+		}
+	}
+
+	if sel.Typename {
+		cols = append(cols, prefix+"__typename")
+	}
+
+	for _, id := range sel.Children {
+		csel := &qc.Selects[id]
+
+		switch {
+		case csel.SkipRender == qcode.SkipTypeDrop,
+			csel.SkipRender == qcode.SkipTypeRemote:
+			continue
+
+		// lists and unions are exported as a single json value
+		case csel.Singular && csel.Type != qcode.SelTypeUnion &&
+			csel.SkipRender == qcode.SkipTypeNone:
+			cols = appendColumns(cols, qc, csel, prefix+csel.FieldName+".")
+
+		default:
+			cols = append(cols, prefix+csel.FieldName)
+		}
+
+		if csel.Paging.Cursor {
+			cols = append(cols, prefix+csel.FieldName+"_cursor")
+		}
+	}
+	return cols
+}
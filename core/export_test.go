@@ -0,0 +1,52 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testExporter struct{}
+
+func (testExporter) Columns(cols []string) error   { return nil }
+func (testExporter) Row(row json.RawMessage) error { return nil }
+
+func TestExportCompile(t *testing.T) {
+	g := newTestAllowListGraphJin(t)
+	gj := g.Load().(*graphjinEngine)
+
+	item, err := gj.allowList.GetByName("getProducts", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gj.newGraphqlReq(nil, "", item.Name, nil, nil)
+	r.Set(item)
+	r.export = testExporter{}
+
+	c := context.WithValue(context.Background(), UserIDKey, 1)
+	s, err := newGState(c, gj, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.compileQueryForRole(); err != nil {
+		t.Fatal(err)
+	}
+
+	if sql := s.sql(); strings.Contains(sql, "jsonb_agg(__sj_0") {
+		t.Errorf("expected the rows not to be aggregated: %s", sql)
+	}
+
+	cols := exportColumns(s.qcode())
+	exp := []string{"id", "name", "user.full_name"}
+
+	if !reflect.DeepEqual(cols, exp) {
+		t.Errorf("expected columns %v got %v", exp, cols)
+	}
+
+	if !strings.HasSuffix(s.key(), ":export") {
+		t.Errorf("expected the export to be cached separately: %s", s.key())
+	}
+}
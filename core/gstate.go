@@ -16,14 +16,17 @@ import (
 )
 
 type gstate struct {
-	gj    *graphjinEngine
-	r     GraphqlReq
-	cs    *cstate
-	vmap  map[string]json.RawMessage
-	data  []byte
-	dhash [sha256.Size]byte
-	role  string
-	verrs []qcode.ValidErr
+	gj     *graphjinEngine
+	r      GraphqlReq
+	cs     *cstate
+	vmap   map[string]json.RawMessage
+	data   []byte
+	dhash  [sha256.Size]byte
+	role   string
+	scopes []string
+	pkey   string
+	verrs  []qcode.ValidErr
+	parts  []stmt
 }
 
 type cstate struct {
@@ -38,6 +41,8 @@ type stmt struct {
 	qc   *qcode.QCode
 	md   psql.Metadata
 	sql  string
+	// statements for the initial result and the deferred parts
+	parts []stmt
 }
 
 func newGState(c context.Context, gj *graphjinEngine, r GraphqlReq) (s gstate, err error) {
@@ -55,6 +60,10 @@ func newGState(c context.Context, gj *graphjinEngine, r GraphqlReq) (s gstate, e
 		}
 	}
 
+	if v, ok := c.Value(UserScopesKey).([]string); ok {
+		s.scopes = v
+	}
+
 	// convert variable json to a go map also decrypted encrypted values
 	if len(r.vars) != 0 {
 		var vars json.RawMessage
@@ -71,15 +80,27 @@ func newGState(c context.Context, gj *graphjinEngine, r GraphqlReq) (s gstate, e
 	return
 }
 
-func (s *gstate) compile() (err error) {
+func (s *gstate) compile(c context.Context) (err error) {
 	if !s.gj.prodSec {
 		err = s.compileQueryForRole()
+	} else {
+		// In production mode and compile and cache the result
+		// In production mode the query is derived from the allow list
+		err = s.compileQueryForRoleOnce()
+	}
+	if err != nil {
 		return
 	}
 
-	// In production mode and compile and cache the result
-	// In production mode the query is derived from the allow list
-	err = s.compileQueryForRoleOnce()
+	// The compiled query is shared by all users of a role
+	// so the scopes and policy are checked for every request
+	if err = s.cs.st.qc.CheckScopes(s.scopes); err != nil {
+		return
+	}
+
+	if s.gj.policy != nil {
+		err = s.evalPolicy(c)
+	}
 	return
 }
 
@@ -121,13 +142,10 @@ func (s *gstate) compileQueryForRole() (err error) {
 		return
 	}
 
-	var w bytes.Buffer
-	if st.md, err = s.gj.psqlCompiler.Compile(&w, st.qc); err != nil {
+	if err = s.compileStmt(&st); err != nil {
 		return
 	}
 
-	st.sql = w.String()
-
 	if s.cs == nil {
 		s.cs = &cstate{st: st}
 	} else {
@@ -137,6 +155,33 @@ func (s *gstate) compileQueryForRole() (err error) {
 	return
 }
 
+// compileStmt compiles the sql for the compiled query of the statement
+func (s *gstate) compileStmt(st *stmt) (err error) {
+	var w bytes.Buffer
+	if s.r.export != nil {
+		if st.qc.Remotes != 0 {
+			err = fmt.Errorf("export: remote joins are not supported: %s", st.qc.Name)
+			return
+		}
+		st.md, err = s.gj.psqlCompiler.CompileExport(&w, st.qc)
+	} else {
+		st.md, err = s.gj.psqlCompiler.Compile(&w, st.qc)
+	}
+	if err != nil {
+		return
+	}
+
+	st.sql = w.String()
+	st.parts = nil
+
+	if len(st.qc.Defers) != 0 && s.r.export == nil {
+		if st.parts, err = s.compileParts(*st); err != nil {
+			return
+		}
+	}
+	return
+}
+
 func (s *gstate) compileAndExecuteWrapper(c context.Context) (err error) {
 	if err = s.compileAndExecute(c); err != nil {
 		return
@@ -200,13 +245,26 @@ func (s *gstate) compileAndExecute(c context.Context) (err error) {
 	}
 
 	// compile query for the role
-	if err = s.compile(); err != nil {
+	if err = s.compile(c); err != nil {
 		return
 	}
 
+	// use the statement for the initial result when
+	// the deferred parts are delivered incrementally
+	if s.r.incremental && len(s.cs.st.parts) != 0 {
+		s.parts = s.cs.st.parts[1:]
+		s.cs = &cstate{st: s.cs.st.parts[0]}
+	}
+
 	// set default variables
 	s.setDefaultVars()
 
+	// stream the rows when exporting
+	if s.r.export != nil {
+		err = s.executeExport(c, conn)
+		return
+	}
+
 	// execute query
 	err = s.execute(c, conn)
 	return
@@ -362,5 +420,8 @@ func (s *gstate) tx() (tx *sql.Tx) {
 
 func (s *gstate) key() (key string) {
 	key = s.r.namespace + s.r.name + s.role
+	if s.r.export != nil {
+		key += ":export"
+	}
 	return
 }
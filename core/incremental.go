@@ -0,0 +1,206 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+
+	"github.com/dosco/graphjin/core/v3/internal/qcode"
+)
+
+// Incremental is a part of the result delivered after the initial result,
+// it holds either the data of a deferred fragment or the items of a streamed list
+type Incremental struct {
+	Data   json.RawMessage `json:"data,omitempty"`
+	Items  json.RawMessage `json:"items,omitempty"`
+	Path   []interface{}   `json:"path"`
+	Label  string          `json:"label,omitempty"`
+	Errors []Error         `json:"errors,omitempty"`
+}
+
+// compileParts compiles the statement for the initial result followed
+// by a statement for each of the deferred parts
+func (s *gstate) compileParts(st stmt) (parts []stmt, err error) {
+	init, dl := st.qc.Incremental()
+
+	for _, qc := range append([]*qcode.QCode{init}, dl...) {
+		ps := stmt{role: st.role, roc: st.roc, qc: qc}
+
+		var w bytes.Buffer
+		if ps.md, err = s.gj.psqlCompiler.Compile(&w, qc); err != nil {
+			return
+		}
+		ps.sql = w.String()
+		parts = append(parts, ps)
+	}
+	return
+}
+
+// executeParts executes the deferred parts and returns a channel that
+// the results are delivered on as they become available
+func (s *gstate) executeParts(c context.Context) <-chan *Result {
+	ch := make(chan *Result, len(s.parts))
+	n := len(s.parts)
+
+	var mu sync.Mutex
+	send := func(res *Result) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		n--
+		hasNext := (n != 0)
+		res.HasNext = &hasNext
+
+		ch <- res
+		if !hasNext {
+			close(ch)
+		}
+	}
+
+	run := func(st stmt) {
+		res := &Result{
+			namespace: s.r.namespace,
+			operation: s.r.operation,
+			name:      s.r.name,
+			sql:       st.sql,
+			role:      s.role,
+		}
+
+		ps := *s
+		ps.cs = &cstate{st: st}
+		ps.data = nil
+		ps.parts = nil
+
+		err := ps.executePart(c)
+		if err == nil {
+			res.Incremental, err = newIncremental(st.qc, ps.data)
+		}
+		if err != nil {
+			res.Errors = newError(err)
+		}
+		send(res)
+	}
+
+	for _, st := range s.parts {
+		go run(st)
+	}
+	return ch
+}
+
+// executePart executes the statement of a deferred part
+func (s *gstate) executePart(c context.Context) (err error) {
+	var conn *sql.Conn
+
+	c1, span := s.gj.spanStart(c, "Get Connection")
+	defer span.End()
+
+	err = retryOperation(c1, func() (err1 error) {
+		conn, err1 = s.gj.db.Conn(c1)
+		return
+	})
+	if err != nil {
+		span.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	if s.gj.conf.SetUserID {
+		err = retryOperation(c, func() (err1 error) {
+			return s.setLocalUserID(c, conn)
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	err = s.execute(c, conn)
+	return
+}
+
+// newIncremental extracts the incremental results of a deferred part
+// from the data returned by its statement
+func newIncremental(qc *qcode.QCode, data json.RawMessage) (inc []Incremental, err error) {
+	d := qc.Defer
+
+	var sels []*qcode.Select
+	for id := d.ParentID; id != -1; id = qc.Selects[id].ParentID {
+		sels = append([]*qcode.Select{&qc.Selects[id]}, sels...)
+	}
+
+	err = walkPath(sels, data, []interface{}{}, func(path []interface{}, v json.RawMessage) error {
+		if !d.Stream {
+			inc = append(inc, Incremental{Data: v, Path: path, Label: d.Label})
+			return nil
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(v, &obj); err != nil {
+			return err
+		}
+		sel := &qc.Selects[d.SelID]
+		items := obj[sel.FieldName]
+
+		if len(items) == 0 || bytes.Equal(items, []byte("[]")) ||
+			bytes.Equal(items, []byte("null")) {
+			return nil
+		}
+		inc = append(inc, Incremental{
+			Items: items,
+			Path:  appendPath(path, sel.FieldName, int(d.InitialCount)),
+			Label: d.Label,
+		})
+		return nil
+	})
+	return
+}
+
+// walkPath walks down the selectors in the data and calls fn with
+// the path and value of every object found at the end
+func walkPath(sels []*qcode.Select,
+	v json.RawMessage,
+	path []interface{},
+	fn func([]interface{}, json.RawMessage) error,
+) error {
+	if len(v) == 0 || bytes.Equal(v, []byte("null")) {
+		return nil
+	}
+
+	if len(sels) == 0 {
+		return fn(path, v)
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(v, &obj); err != nil {
+		return err
+	}
+
+	sel := sels[0]
+	cv := obj[sel.FieldName]
+
+	if sel.Singular {
+		return walkPath(sels[1:], cv, appendPath(path, sel.FieldName), fn)
+	}
+
+	var list []json.RawMessage
+	if len(cv) != 0 {
+		if err := json.Unmarshal(cv, &list); err != nil {
+			return err
+		}
+	}
+
+	for i, item := range list {
+		err := walkPath(sels[1:], item, appendPath(path, sel.FieldName, i), fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendPath(path []interface{}, v ...interface{}) []interface{} {
+	p := make([]interface{}, 0, len(path)+len(v))
+	p = append(p, path...)
+	return append(p, v...)
+}
@@ -356,6 +356,7 @@ func addRole(qc *qcode.Compiler, r Role, t RoleTable, defaultBlock bool) error {
 			Columns:          t.Query.Columns,
 			DisableFunctions: t.Query.DisableFunctions,
 			Block:            t.Query.Block,
+			Scopes:           t.Query.Scopes,
 		}
 	}
 
@@ -364,6 +365,7 @@ func addRole(qc *qcode.Compiler, r Role, t RoleTable, defaultBlock bool) error {
 			Columns: t.Insert.Columns,
 			Presets: t.Insert.Presets,
 			Block:   t.Insert.Block,
+			Scopes:  t.Insert.Scopes,
 		}
 	}
 
@@ -373,6 +375,7 @@ func addRole(qc *qcode.Compiler, r Role, t RoleTable, defaultBlock bool) error {
 			Columns: t.Update.Columns,
 			Presets: t.Update.Presets,
 			Block:   t.Update.Block,
+			Scopes:  t.Update.Scopes,
 		}
 	}
 
@@ -382,6 +385,7 @@ func addRole(qc *qcode.Compiler, r Role, t RoleTable, defaultBlock bool) error {
 			Columns: t.Upsert.Columns,
 			Presets: t.Upsert.Presets,
 			Block:   t.Upsert.Block,
+			Scopes:  t.Upsert.Scopes,
 		}
 	}
 
@@ -390,6 +394,7 @@ func addRole(qc *qcode.Compiler, r Role, t RoleTable, defaultBlock bool) error {
 			Filters: t.Delete.Filters,
 			Columns: t.Delete.Columns,
 			Block:   t.Delete.Block,
+			Scopes:  t.Delete.Scopes,
 		}
 	}
 
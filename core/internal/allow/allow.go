@@ -7,7 +7,9 @@ import (
 	"fmt"
 	_log "log"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/dosco/graphjin/core/v3/internal/graph"
 	lru "github.com/hashicorp/golang-lru"
@@ -19,6 +21,11 @@ type FS interface {
 	Exists(path string) (exists bool, err error)
 }
 
+// ListFS is implemented by filesystems that can list the files in a folder
+type ListFS interface {
+	List(path string) (names []string, err error)
+}
+
 var ErrUnknownGraphQLQuery = errors.New("unknown graphql query")
 
 const (
@@ -43,6 +50,10 @@ type List struct {
 	cache    *lru.TwoQueueCache
 	saveChan chan Item
 	fs       FS
+
+	// hashes of the persisted queries mapped to their names
+	pmu       sync.RWMutex
+	persisted map[string]string
 }
 
 // New creates a new allow list
@@ -121,6 +132,35 @@ func (al *List) GetByName(name string, useCache bool) (item Item, err error) {
 	return
 }
 
+// ListAll returns all the queries in the allow list sorted by name
+func (al *List) ListAll() (items []Item, err error) {
+	lfs, ok := al.fs.(ListFS)
+	if !ok {
+		err = errors.New("allow list: filesystem does not support listing queries")
+		return
+	}
+
+	var names []string
+	if names, err = lfs.List(QUERY_PATH); err != nil {
+		return
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		ext := filepath.Ext(n)
+		if ext != ".gql" && ext != ".graphql" {
+			continue
+		}
+
+		var item Item
+		if item, err = al.get(QUERY_PATH, strings.TrimSuffix(n, ext), ext, false); err != nil {
+			return
+		}
+		items = append(items, item)
+	}
+	return
+}
+
 // get returns a query by name
 func (al *List) get(queryPath, name, ext string, useCache bool) (item Item, err error) {
 	queryNS, queryName := splitName(name)
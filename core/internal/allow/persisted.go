@@ -0,0 +1,176 @@
+package allow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/dosco/graphjin/core/v3/internal/graph"
+)
+
+// the hyphen keeps the file from clashing with the
+// variables file of a query since it's not valid in a name
+const PERSISTED_FILE = QUERY_PATH + "/persisted-queries.json"
+
+var ErrUnknownPersistedQuery = errors.New("unknown persisted query")
+
+type apolloManifest struct {
+	Format     string `json:"format"`
+	Operations []struct {
+		ID   string `json:"id"`
+		Body string `json:"body"`
+	} `json:"operations"`
+}
+
+// ParseManifest parses a persisted query manifest into a map of hashes
+// and queries, both the Apollo manifest format and a json object of hashes
+// and queries (Relay) are supported
+func ParseManifest(data []byte) (queries map[string]string, err error) {
+	var am apolloManifest
+	if err = json.Unmarshal(data, &am); err == nil && am.Format != "" {
+		queries = make(map[string]string, len(am.Operations))
+		for _, op := range am.Operations {
+			queries[op.ID] = op.Body
+		}
+		return
+	}
+
+	if err = json.Unmarshal(data, &queries); err != nil {
+		err = fmt.Errorf("persisted queries: invalid manifest: %w", err)
+	}
+	return
+}
+
+// Import adds the queries of a persisted query manifest to the allow list
+// and returns their names. The hashes are saved along with the queries so
+// that the queries can later be fetched by their hash.
+func (al *List) Import(queries map[string]string, namespace string) (names []string, err error) {
+	if err = al.loadPersisted(); err != nil {
+		return
+	}
+
+	hashes := make([]string, 0, len(queries))
+	for k := range queries {
+		hashes = append(hashes, k)
+	}
+	sort.Strings(hashes)
+
+	items := make(map[string]Item, len(queries))
+	pmap := make(map[string]string, len(queries))
+
+	for _, hash := range hashes {
+		query := queries[hash]
+
+		var h graph.FPInfo
+		if h, err = graph.FastParse(query); err != nil {
+			err = fmt.Errorf("persisted query '%s': %w", hash, err)
+			return
+		}
+
+		if h.Name == "" {
+			err = fmt.Errorf("persisted query '%s': only named queries can be imported", hash)
+			return
+		}
+
+		name := h.Name
+		if namespace != "" {
+			name = namespace + "." + h.Name
+		}
+
+		if v, ok := items[name]; ok {
+			if string(v.Query) != query {
+				err = fmt.Errorf("persisted query '%s': query name '%s' is used by another query",
+					hash, name)
+				return
+			}
+		} else {
+			items[name] = Item{
+				Namespace: namespace,
+				Operation: h.Operation,
+				Name:      h.Name,
+				Query:     []byte(query),
+			}
+			names = append(names, name)
+		}
+		pmap[hash] = name
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		if err = al.saveItem(items[name]); err != nil {
+			return
+		}
+		al.cache.Remove(name)
+	}
+
+	al.pmu.Lock()
+	defer al.pmu.Unlock()
+
+	for k, v := range pmap {
+		al.persisted[k] = v
+	}
+
+	b, err := json.MarshalIndent(al.persisted, "", "  ")
+	if err != nil {
+		return
+	}
+	err = al.fs.Put(PERSISTED_FILE, b)
+	return
+}
+
+// GetByHash returns a query imported from a persisted query manifest by its hash
+func (al *List) GetByHash(hash string) (item Item, err error) {
+	if err = al.loadPersisted(); err != nil {
+		return
+	}
+
+	al.pmu.RLock()
+	name, ok := al.persisted[hash]
+	al.pmu.RUnlock()
+
+	if !ok {
+		err = ErrUnknownPersistedQuery
+		return
+	}
+	return al.GetByName(name, true)
+}
+
+// loadPersisted loads the hashes of the persisted queries
+func (al *List) loadPersisted() (err error) {
+	al.pmu.RLock()
+	loaded := al.persisted != nil
+	al.pmu.RUnlock()
+
+	if loaded {
+		return
+	}
+
+	al.pmu.Lock()
+	defer al.pmu.Unlock()
+
+	if al.persisted != nil {
+		return
+	}
+
+	pmap := make(map[string]string)
+
+	ok, err := al.fs.Exists(PERSISTED_FILE)
+	if err != nil {
+		return
+	}
+
+	if ok {
+		var b []byte
+		if b, err = al.fs.Get(PERSISTED_FILE); err != nil {
+			return
+		}
+		if err = json.Unmarshal(b, &pmap); err != nil {
+			err = fmt.Errorf("persisted queries: %w", err)
+			return
+		}
+	}
+
+	al.persisted = pmap
+	return
+}
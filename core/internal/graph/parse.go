@@ -438,6 +438,32 @@ func (p *Parser) parseFragmentFields(st *Stack, fields []Field) ([]Field, error)
 			}
 		}
 
+	} else if p.peek(itemDirective, itemObjOpen) {
+		// inline fragment without a type condition
+		// ... @defer { fields }
+		var dirs []Directive
+		for p.peek(itemDirective) {
+			p.ignore()
+			if dirs, err = p.parseDirective(dirs); err != nil {
+				return nil, err
+			}
+		}
+
+		if !p.peek(itemObjOpen) {
+			return nil, fmt.Errorf("expecting a '{', got: %s", p.next())
+		}
+		p.ignore()
+
+		var ff []Field
+		if ff, err = p.parseFields(ff); err != nil {
+			return nil, err
+		}
+
+		if p.peek(itemObjClose) {
+			p.ignore()
+		}
+		fields = appendFragFields(fields, ff, pid, dirs)
+
 	} else {
 		if !p.peek(itemName) {
 			return nil, fmt.Errorf("expecting a fragment name, got: %s", p.next())
@@ -450,48 +476,71 @@ func (p *Parser) parseFragmentFields(st *Stack, fields []Field) ([]Field, error)
 			return nil, fmt.Errorf("fragment not defined: %s", name)
 		}
 
-		ff := fr.Fields
+		// directives on the fragment spread
+		// ...userFields @defer
+		var dirs []Directive
+		for p.peek(itemDirective) {
+			p.ignore()
+			if dirs, err = p.parseDirective(dirs); err != nil {
+				return nil, err
+			}
+		}
+		fields = appendFragFields(fields, fr.Fields, pid, dirs)
+	}
 
-		n := int32(len(fields))
-		fields = append(fields, ff...)
+	return fields, nil
+}
+
+// appendFragFields adds the fields of a fragment to the fields of the parent
+// and copies over directives set on the fragment spread to its top-level fields
+func appendFragFields(fields, ff []Field, pid int32, dirs []Directive) []Field {
+	n := int32(len(fields))
+	fields = append(fields, ff...)
 
-		for i := 0; i < len(ff); i++ {
-			k := (n + int32(i))
-			f := &fields[k]
-			f.ID = int32(k)
+	for i := 0; i < len(ff); i++ {
+		k := (n + int32(i))
+		f := &fields[k]
+		f.ID = int32(k)
 
-			// Nothing to do here if fields was originally empty
-			if n != 0 {
-				// If this is the top-level, point the parent to the parent of the
-				// previous field.
-				if f.ParentID == -1 {
-					if pid != -1 {
-						f.ParentID = pid
-						fields[pid].Children = append(fields[pid].Children, f.ID)
-					}
+		top := (ff[i].ParentID == -1)
 
-					// Update all the other parents id's by our new place in this new array
-				} else {
-					f.ParentID += n
+		// Nothing to do here if fields was originally empty
+		if n != 0 {
+			// If this is the top-level, point the parent to the parent of the
+			// previous field.
+			if f.ParentID == -1 {
+				if pid != -1 {
+					f.ParentID = pid
+					fields[pid].Children = append(fields[pid].Children, f.ID)
 				}
+
+				// Update all the other parents id's by our new place in this new array
+			} else {
+				f.ParentID += n
 			}
+		}
 
-			// Copy over children since fields append is not a deep copy
-			f.Children = make([]int32, len(f.Children))
-			copy(f.Children, ff[i].Children)
+		// Copy over children since fields append is not a deep copy
+		f.Children = make([]int32, len(f.Children))
+		copy(f.Children, ff[i].Children)
 
-			// Copy over args since args append is not a deep copy
-			f.Args = make([]Arg, len(f.Args))
-			copy(f.Args, ff[i].Args)
+		// Copy over args since args append is not a deep copy
+		f.Args = make([]Arg, len(f.Args))
+		copy(f.Args, ff[i].Args)
 
-			// Update all the children which is needed.
-			for j := range f.Children {
-				f.Children[j] += n
-			}
+		// Copy over directives and add the ones from the spread
+		if top && len(dirs) != 0 {
+			f.Directives = make([]Directive, 0, len(ff[i].Directives)+len(dirs))
+			f.Directives = append(f.Directives, ff[i].Directives...)
+			f.Directives = append(f.Directives, dirs...)
 		}
-	}
 
-	return fields, nil
+		// Update all the children which is needed.
+		for j := range f.Children {
+			f.Children[j] += n
+		}
+	}
+	return fields
 }
 
 func (p *Parser) parseField(f *Field) error {
@@ -124,27 +124,14 @@ func (c *expContext) renderOp(ex *qcode.Exp) {
 		return
 	}
 
-	if ex.Left.Col.Name != "" {
-		var table string
-		if ex.Left.Table == "" {
-			table = ex.Left.Col.Table
-		} else {
-			table = ex.Left.Table
-		}
-
-		var colName string
-		if ex.Left.ColName != "" {
-			colName = ex.Left.ColName
-		} else {
-			colName = ex.Left.Col.Name
-		}
+	if ex.Geo != nil {
+		c.renderGeoOp(ex)
+		return
+	}
 
+	if ex.Left.Col.Name != "" {
 		c.w.WriteString(`((`)
-		if ex.Left.ID == -1 {
-			c.colWithTable(table, colName)
-		} else {
-			c.colWithTableID(table, ex.Left.ID, colName)
-		}
+		c.renderExpLeftCol(ex)
 		c.w.WriteString(`) `)
 	}
 
@@ -253,32 +240,19 @@ func (c *expContext) renderOp(ex *qcode.Exp) {
 	case qcode.OpTsQuery:
 		switch c.ct {
 		case "mysql":
-			// MATCH (name) AGAINST ('phone' IN BOOLEAN MODE);
-			c.w.WriteString(`(MATCH(`)
-			for i, col := range c.ti.FullText {
-				if i != 0 {
-					c.w.WriteString(`, `)
-				}
-				c.colWithTable(c.ti.Name, col.Name)
-			}
-			c.w.WriteString(`) AGAINST (`)
-			c.renderParam(Param{Name: ex.Right.Val, Type: "text"})
-			c.w.WriteString(` IN NATURAL LANGUAGE MODE))`)
+			c.w.WriteString(`(`)
+			c.renderMysqlMatch(c.ti, c.ti.Name, ex.Search)
+			c.w.WriteString(`)`)
 
 		default:
-			// fmt.Fprintf(w, `(("%s") @@ websearch_to_tsquery('%s'))`, c.ti.TSVCol, val.Val)
 			c.w.WriteString(`((`)
 			for i, col := range c.ti.FullText {
 				if i != 0 {
 					c.w.WriteString(` OR (`)
 				}
 				c.colWithTable(c.ti.Name, col.Name)
-				if c.cv >= 110000 {
-					c.w.WriteString(`) @@ websearch_to_tsquery(`)
-				} else {
-					c.w.WriteString(`) @@ to_tsquery(`)
-				}
-				c.renderParam(Param{Name: ex.Right.Val, Type: "text"})
+				c.w.WriteString(`) @@ `)
+				c.renderTsQuery(ex.Search)
 				c.w.WriteString(`)`)
 			}
 			c.w.WriteString(`)`)
@@ -296,6 +270,28 @@ func (c *expContext) renderOp(ex *qcode.Exp) {
 	c.w.WriteString(`)`)
 }
 
+func (c *expContext) renderExpLeftCol(ex *qcode.Exp) {
+	var table string
+	if ex.Left.Table == "" {
+		table = ex.Left.Col.Table
+	} else {
+		table = ex.Left.Table
+	}
+
+	var colName string
+	if ex.Left.ColName != "" {
+		colName = ex.Left.ColName
+	} else {
+		colName = ex.Left.Col.Name
+	}
+
+	if ex.Left.ID == -1 {
+		c.colWithTable(table, colName)
+	} else {
+		c.colWithTableID(table, ex.Left.ID, colName)
+	}
+}
+
 func (c *expContext) renderValPrefix(ex *qcode.Exp) bool {
 	switch {
 	case c.ct == "mysql" && (ex.Op == qcode.OpHasKey ||
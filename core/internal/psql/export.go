@@ -0,0 +1,92 @@
+//nolint:errcheck
+package psql
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dosco/graphjin/core/v3/internal/qcode"
+)
+
+// CompileExport compiles a query with a single root selector into sql that
+// returns a row with the json of each record of the selector instead of a
+// single json value, this allows the rows to be read one at a time from the
+// database cursor. The default limit does not apply to the root selector.
+func (co *Compiler) CompileExport(w *bytes.Buffer, qc *qcode.QCode) (Metadata, error) {
+	var md Metadata
+
+	if qc == nil {
+		return md, fmt.Errorf("qcode is nil")
+	}
+
+	if qc.Type != qcode.QTQuery {
+		return md, fmt.Errorf("export: only queries can be exported: %s", qc.Name)
+	}
+
+	var sel *qcode.Select
+	for _, id := range qc.Roots {
+		if qc.Selects[id].SkipRender == qcode.SkipTypeDrop {
+			continue
+		}
+		if sel != nil {
+			return md, fmt.Errorf("export: query must have a single root selector: %s", qc.Name)
+		}
+		sel = &qc.Selects[id]
+	}
+
+	switch {
+	case sel == nil:
+		return md, fmt.Errorf("export: query has no root selector: %s", qc.Name)
+
+	case sel.SkipRender != qcode.SkipTypeNone:
+		return md, fmt.Errorf("export: selector not allowed: %s", sel.FieldName)
+
+	case sel.Type == qcode.SelTypeUnion:
+		return md, fmt.Errorf("export: union selectors cannot be exported: %s", sel.FieldName)
+	}
+
+	// all the rows are exported unless the query or the role sets a limit
+	if sel.Paging.DefaultLimit && sel.Paging.LimitVar == "" {
+		sel.Paging.NoLimit = true
+	}
+
+	md.ct = qc.Schema.DBType()
+
+	c := &compilerContext{
+		md:       &md,
+		w:        w,
+		qc:       qc,
+		Compiler: co,
+	}
+
+	w.WriteString(`/* action='` + qc.Name + `',controller='graphql',framework='graphjin' */ `)
+
+	// the row selector is wrapped in place of the aggregate
+	// used to build the json array
+	if !sel.Singular {
+		c.w.WriteString(`SELECT __sj_`)
+		int32String(c.w, sel.ID)
+		c.w.WriteString(`.json AS json FROM (`)
+	}
+	c.renderSelect(sel)
+
+	st := NewIntStack()
+	for _, cid := range sel.Children {
+		child := &c.qc.Selects[cid]
+
+		if child.SkipRender != qcode.SkipTypeNone {
+			continue
+		}
+		st.Push(child.ID + closeBlock)
+		st.Push(child.ID)
+	}
+	c.renderQuery(st, true)
+	c.renderSelectClose(sel)
+
+	if !sel.Singular && sel.FieldFilter.Exp != nil {
+		c.w.WriteString(` WHERE `)
+		c.renderExp(sel.Ti, sel.FieldFilter.Exp, false)
+	}
+
+	return md, nil
+}
@@ -0,0 +1,117 @@
+package psql_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func compileGQLToExport(t *testing.T, gql string) (string, error) {
+	qc, err := qcompile.Compile([]byte(gql), nil, "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var w bytes.Buffer
+	_, err = pcompile.CompileExport(&w, qc)
+	return w.String(), err
+}
+
+func exportPlural(t *testing.T) {
+	gql := `query {
+		products(limit: 10) {
+			id
+			name
+			user {
+				id
+				email
+			}
+		}
+	}`
+
+	sql, err := compileGQLToExport(t, gql)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(sql, "jsonb_agg(__sj_0") || strings.Contains(sql, "__root") {
+		t.Fatalf("expected the rows of the root selector not to be aggregated: %s", sql)
+	}
+
+	if !strings.Contains(sql, "SELECT __sj_0.json AS json FROM (") {
+		t.Fatalf("expected a row for each product: %s", sql)
+	}
+}
+
+func exportSingular(t *testing.T) {
+	gql := `query {
+		products(id: 15) {
+			id
+			name
+		}
+	}`
+
+	sql, err := compileGQLToExport(t, gql)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(sql, "SELECT to_jsonb(__sr_0.*) AS json FROM (") {
+		t.Fatalf("expected a single row for the product: %s", sql)
+	}
+}
+
+func exportNoLimit(t *testing.T) {
+	gql := `query {
+		products {
+			id
+			name
+		}
+	}`
+
+	sql, err := compileGQLToExport(t, gql)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(sql, "LIMIT") {
+		t.Fatalf("expected all the products to be exported: %s", sql)
+	}
+
+	gql = `query {
+		products(limit: 50) {
+			id
+			name
+		}
+	}`
+
+	if sql, err = compileGQLToExport(t, gql); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(sql, "LIMIT 50") {
+		t.Fatalf("expected the limit of the query to be used: %s", sql)
+	}
+}
+
+func exportMultiRoot(t *testing.T) {
+	gql := `query {
+		products {
+			id
+		}
+		users {
+			id
+		}
+	}`
+
+	if _, err := compileGQLToExport(t, gql); err == nil {
+		t.Fatal("expected an error for a query with multiple root selectors")
+	}
+}
+
+func TestCompileExport(t *testing.T) {
+	t.Run("exportPlural", exportPlural)
+	t.Run("exportSingular", exportSingular)
+	t.Run("exportNoLimit", exportNoLimit)
+	t.Run("exportMultiRoot", exportMultiRoot)
+}
@@ -1,46 +1,93 @@
 package psql
 
-import "github.com/dosco/graphjin/core/v3/internal/qcode"
+import (
+	"github.com/dosco/graphjin/core/v3/internal/qcode"
+	"github.com/dosco/graphjin/core/v3/internal/sdata"
+)
+
+func (c *compilerContext) renderFunctionBucket(f qcode.Field) {
+	col, iv := f.Args[0].Col, f.Args[1]
 
-func (c *compilerContext) renderFunctionSearchRank(sel *qcode.Select, f qcode.Field) {
 	if c.ct == "mysql" {
-		c.w.WriteString(`0`)
+		c.renderMysqlBucket(col, iv)
 		return
 	}
 
-	c.w.WriteString(`ts_rank(`)
-	for i, col := range sel.Ti.FullText {
-		if i != 0 {
-			c.w.WriteString(` || `)
-		}
-		c.colWithTable(sel.Table, col.Name)
-	}
-	if c.cv >= 110000 {
-		c.w.WriteString(`, websearch_to_tsquery(`)
-	} else {
-		c.w.WriteString(`, to_tsquery(`)
+	switch {
+	case iv.Name == "interval":
+		c.w.WriteString(`date_trunc(`)
+		c.squoted(iv.Val)
+		c.w.WriteString(`, `)
+		c.colWithTable(col.Table, col.Name)
+		c.w.WriteString(`)`)
+
+	case c.cv >= 140000:
+		c.w.WriteString(`date_bin(`)
+		c.squoted(iv.Val + ` seconds`)
+		c.w.WriteString(`, `)
+		c.colWithTable(col.Table, col.Name)
+		c.w.WriteString(`, '2001-01-01')`)
+
+	default:
+		c.w.WriteString(`to_timestamp(floor(extract(epoch from `)
+		c.colWithTable(col.Table, col.Name)
+		c.w.WriteString(`) / `)
+		c.w.WriteString(iv.Val)
+		c.w.WriteString(`) * `)
+		c.w.WriteString(iv.Val)
+		c.w.WriteString(`)`)
 	}
-	arg, _ := sel.GetInternalArg("search")
-	c.renderParam(Param{Name: arg.Val, Type: "text"})
-	c.w.WriteString(`))`)
 }
 
-func (c *compilerContext) renderFunctionSearchHeadline(sel *qcode.Select, f qcode.Field) {
-	if c.ct == "mysql" {
-		c.w.WriteString(`''`)
-		return
+func (c *compilerContext) renderMysqlBucket(col sdata.DBColumn, iv qcode.Arg) {
+	var format string
+
+	switch iv.Val {
+	case "second":
+		format = `%Y-%m-%d %H:%i:%s`
+	case "minute":
+		format = `%Y-%m-%d %H:%i:00`
+	case "hour":
+		format = `%Y-%m-%d %H:00:00`
+	case "day":
+		format = `%Y-%m-%d`
+	case "month":
+		format = `%Y-%m-01`
+	case "year":
+		format = `%Y-01-01`
 	}
 
-	c.w.WriteString(`ts_headline(`)
-	c.colWithTable(sel.Table, f.Col.Name)
-	if c.cv >= 110000 {
-		c.w.WriteString(`, websearch_to_tsquery(`)
-	} else {
-		c.w.WriteString(`, to_tsquery(`)
+	switch {
+	case format != "":
+		c.w.WriteString(`CAST(DATE_FORMAT(`)
+		c.colWithTable(col.Table, col.Name)
+		c.w.WriteString(`, `)
+		c.squoted(format)
+		c.w.WriteString(`) AS DATETIME)`)
+
+	case iv.Val == "week":
+		c.w.WriteString(`CAST(DATE_SUB(DATE(`)
+		c.colWithTable(col.Table, col.Name)
+		c.w.WriteString(`), INTERVAL WEEKDAY(`)
+		c.colWithTable(col.Table, col.Name)
+		c.w.WriteString(`) DAY) AS DATETIME)`)
+
+	case iv.Val == "quarter":
+		c.w.WriteString(`CAST(MAKEDATE(YEAR(`)
+		c.colWithTable(col.Table, col.Name)
+		c.w.WriteString(`), 1) + INTERVAL (QUARTER(`)
+		c.colWithTable(col.Table, col.Name)
+		c.w.WriteString(`) - 1) QUARTER AS DATETIME)`)
+
+	default:
+		c.w.WriteString(`FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(`)
+		c.colWithTable(col.Table, col.Name)
+		c.w.WriteString(`) / `)
+		c.w.WriteString(iv.Val)
+		c.w.WriteString(`) * `)
+		c.w.WriteString(iv.Val)
+		c.w.WriteString(`)`)
 	}
-	arg, _ := sel.GetInternalArg("search")
-	c.renderParam(Param{Name: arg.Val, Type: "text"})
-	c.w.WriteString(`))`)
 }
 
 func (c *compilerContext) renderTableFunction(sel *qcode.Select) {
@@ -49,16 +96,69 @@ func (c *compilerContext) renderTableFunction(sel *qcode.Select) {
 }
 
 func (c *compilerContext) renderFieldFunction(sel *qcode.Select, f qcode.Field) {
+	if f.Window != nil {
+		c.renderWindowFunction(f)
+		return
+	}
+
 	switch f.Func.Name {
 	case "search_rank":
 		c.renderFunctionSearchRank(sel, f)
 	case "search_headline":
 		c.renderFunctionSearchHeadline(sel, f)
+	case "bucket":
+		c.renderFunctionBucket(f)
+	case "distance":
+		c.renderFunctionDistance(f)
 	default:
 		c.renderFunction(f.Func.Name, f.Args)
 	}
 }
 
+func (c *compilerContext) renderWindowFunction(f qcode.Field) {
+	c.w.WriteString(f.Func.Name)
+	c.w.WriteString(`(`)
+	if len(f.Args) != 0 {
+		c.renderFuncArgVal(f.Args[0])
+	}
+	if f.Window.Offset != 0 {
+		c.w.WriteString(`, `)
+		int32String(c.w, f.Window.Offset)
+	}
+	c.w.WriteString(`) OVER (`)
+
+	if len(f.Window.Partition) != 0 {
+		c.w.WriteString(`PARTITION BY `)
+		for i, col := range f.Window.Partition {
+			if i != 0 {
+				c.w.WriteString(`, `)
+			}
+			c.colWithTable(col.Table, col.Name)
+		}
+	}
+
+	if len(f.Window.OrderBy) != 0 {
+		if len(f.Window.Partition) != 0 {
+			c.w.WriteString(` `)
+		}
+		c.w.WriteString(`ORDER BY `)
+		for i, ob := range f.Window.OrderBy {
+			if i != 0 {
+				c.w.WriteString(`, `)
+			}
+			c.colWithTable(ob.Col.Table, ob.Col.Name)
+			c.renderOrder(ob.Order)
+		}
+	}
+
+	// running totals should not treat peer rows (ties in the order)
+	// as a single step so we switch the frame from range to rows
+	if f.Window.Running {
+		c.w.WriteString(` ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW`)
+	}
+	c.w.WriteString(`)`)
+}
+
 func (c *compilerContext) renderFunction(name string, args []qcode.Arg) {
 	c.w.WriteString(name)
 	c.w.WriteString(`(`)
@@ -0,0 +1,120 @@
+package psql
+
+import (
+	"github.com/dosco/graphjin/core/v3/internal/qcode"
+	"github.com/dosco/graphjin/core/v3/internal/sdata"
+)
+
+// GeoJSON coordinates are always in WGS 84
+const geoSRID = `4326`
+
+func (c *expContext) renderGeoOp(ex *qcode.Exp) {
+	col := ex.Left.Col
+
+	switch ex.Op {
+	case qcode.OpStDWithin:
+		c.w.WriteString(`ST_DWithin(`)
+	case qcode.OpStIntersects:
+		c.w.WriteString(`ST_Intersects(`)
+	case qcode.OpStContains:
+		// geography has no ST_Contains, ST_Covers is its equivalent
+		if col.IsGeography() {
+			c.w.WriteString(`ST_Covers(`)
+		} else {
+			c.w.WriteString(`ST_Contains(`)
+		}
+	case qcode.OpStBBox:
+		c.w.WriteString(`((`)
+		c.renderExpLeftCol(ex)
+		c.w.WriteString(`) && `)
+		c.renderGeoBBox(col, ex.Geo)
+		c.w.WriteString(`)`)
+		return
+	}
+
+	c.renderExpLeftCol(ex)
+	c.w.WriteString(`, `)
+	c.renderGeo(col, ex.Geo)
+
+	if ex.Op == qcode.OpStDWithin {
+		c.w.WriteString(`, `)
+		c.renderGeoDistance(ex.Geo)
+	}
+	c.w.WriteString(`)`)
+}
+
+func (c *compilerContext) renderGeo(col sdata.DBColumn, geo *qcode.Geo) {
+	if col.IsGeography() {
+		c.w.WriteString(`CAST(`)
+	}
+	c.w.WriteString(`ST_SetSRID(`)
+
+	switch {
+	case len(geo.Point) == 2:
+		c.w.WriteString(`ST_MakePoint(`)
+		c.w.WriteString(geo.Point[0])
+		c.w.WriteString(`, `)
+		c.w.WriteString(geo.Point[1])
+		c.w.WriteString(`)`)
+
+	case geo.ValType == qcode.ValVar:
+		c.w.WriteString(`ST_GeomFromGeoJSON(CAST(`)
+		c.renderParam(Param{Name: geo.Val, Type: "text"})
+		c.w.WriteString(` AS text))`)
+
+	default:
+		c.w.WriteString(`ST_GeomFromGeoJSON(CAST(`)
+		c.renderValParam("text", geo.Val)
+		c.w.WriteString(` AS text))`)
+	}
+
+	c.w.WriteString(`, ` + geoSRID + `)`)
+	if col.IsGeography() {
+		c.w.WriteString(` AS geography)`)
+	}
+}
+
+func (c *compilerContext) renderGeoBBox(col sdata.DBColumn, geo *qcode.Geo) {
+	if col.IsGeography() {
+		c.w.WriteString(`CAST(`)
+	}
+
+	if geo.ValType == qcode.ValVar {
+		c.w.WriteString(`(SELECT ST_MakeEnvelope(a[1], a[2], a[3], a[4], ` + geoSRID + `) `)
+		c.w.WriteString(`FROM (SELECT CAST(ARRAY(SELECT json_array_elements_text(CAST(`)
+		c.renderParam(Param{Name: geo.Val, Type: "json"})
+		c.w.WriteString(` AS json))) AS float8[]) AS a) AS _gj_bbox)`)
+	} else {
+		c.w.WriteString(`ST_MakeEnvelope(`)
+		for _, v := range geo.BBox {
+			c.w.WriteString(v)
+			c.w.WriteString(`, `)
+		}
+		c.w.WriteString(geoSRID + `)`)
+	}
+
+	if col.IsGeography() {
+		c.w.WriteString(` AS geography)`)
+	}
+}
+
+func (c *compilerContext) renderGeoDistance(geo *qcode.Geo) {
+	if geo.DistType == qcode.ValVar {
+		c.renderParam(Param{Name: geo.Distance, Type: "float8"})
+	} else {
+		c.w.WriteString(geo.Distance)
+	}
+}
+
+func (c *compilerContext) renderFunctionDistance(f qcode.Field) {
+	col := f.Args[0].Col
+	if f.Vector != nil {
+		c.renderVectorDistance(col, f.Vector)
+		return
+	}
+	c.w.WriteString(`ST_Distance(`)
+	c.colWithTable(col.Table, col.Name)
+	c.w.WriteString(`, `)
+	c.renderGeo(col, f.Geo)
+	c.w.WriteString(`)`)
+}
@@ -2,6 +2,7 @@ package psql
 
 import (
 	"bytes"
+	"strconv"
 	"strings"
 )
 
@@ -82,6 +83,13 @@ func (c *compilerContext) renderParam(p Param) {
 	}
 }
 
+// renderValParam binds a literal value from the query as a param
+// so it's never written into the sql
+func (c *compilerContext) renderValParam(typ, val string) {
+	name := "_gj_val_" + strconv.Itoa(len(c.md.params)+1)
+	c.renderParam(Param{Name: name, Type: typ, Value: val, HasValue: true})
+}
+
 func (md Metadata) Params() []Param {
 	return md.params
 }
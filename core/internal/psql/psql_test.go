@@ -89,6 +89,16 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
+	err = qcompile.AddRole("user", "public", "documents", qcode.TRConfig{
+		Query: qcode.QueryConfig{
+			Columns: []string{"id", "title", "embedding"},
+			Filters: []string{"{ owner_id: { eq: $user_id } }"},
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	err = qcompile.AddRole("bad_dude", "public", "users", qcode.TRConfig{
 		Query: qcode.QueryConfig{
 			Filters:          []string{"false"},
@@ -19,6 +19,10 @@ type Param struct {
 	Type      string
 	IsArray   bool
 	IsNotNull bool
+
+	// Value is set for literal values from the query that are bound as params
+	Value    string
+	HasValue bool
 }
 
 type Metadata struct {
@@ -617,20 +621,61 @@ func (c *compilerContext) renderWhere(sel *qcode.Select) {
 }
 
 func (c *compilerContext) renderGroupBy(sel *qcode.Select) {
-	if !sel.GroupCols || len(sel.BCols) == 0 {
+	if !sel.GroupCols {
 		return
 	}
-	c.w.WriteString(` GROUP BY `)
-	for i, col := range sel.BCols {
-		if i != 0 {
+	i := 0
+	for _, col := range sel.BCols {
+		c.renderGroupBySep(i)
+		c.colWithTable(sel.Table, col.Col.Name)
+		i++
+	}
+
+	// time buckets are not columns so group by the bucket
+	// expression itself
+	for _, f := range sel.Fields {
+		if f.Type != qcode.FieldTypeFunc || f.Func.Name != "bucket" {
+			continue
+		}
+		c.renderGroupBySep(i)
+		c.renderFunctionBucket(f)
+		i++
+	}
+}
+
+// renderBucketOrderBy orders grouped time buckets oldest first when
+// no other order is defined so the limit returns a continuous series
+func (c *compilerContext) renderBucketOrderBy(sel *qcode.Select) {
+	if !sel.GroupCols {
+		return
+	}
+	i := 0
+	for _, f := range sel.Fields {
+		if f.Type != qcode.FieldTypeFunc || f.Func.Name != "bucket" {
+			continue
+		}
+		if i == 0 {
+			c.w.WriteString(` ORDER BY `)
+		} else {
 			c.w.WriteString(`, `)
 		}
-		c.colWithTable(sel.Table, col.Col.Name)
+		c.renderFunctionBucket(f)
+		c.w.WriteString(` ASC`)
+		i++
+	}
+}
+
+func (c *compilerContext) renderGroupBySep(i int) {
+	if i == 0 {
+		c.w.WriteString(` GROUP BY `)
+	} else {
+		c.w.WriteString(`, `)
 	}
 }
 
 func (c *compilerContext) renderOrderBy(sel *qcode.Select) {
 	if len(sel.OrderBy) == 0 {
+		c.renderBucketOrderBy(sel)
 		return
 	}
 	c.w.WriteString(` ORDER BY `)
@@ -646,7 +691,17 @@ func (c *compilerContext) renderOrderBy(sel *qcode.Select) {
 			c.squoted(ob.Key)
 			c.w.WriteString(` THEN `)
 		}
-		if ob.Var != "" {
+		if ob.SearchRank {
+			c.renderFunctionSearchRank(sel, qcode.Field{})
+		} else if ob.Vector != nil {
+			c.renderVectorDistance(ob.Col, ob.Vector)
+		} else if ob.Geo != nil {
+			c.w.WriteString(`ST_Distance(`)
+			c.colWithTable(ob.Col.Table, ob.Col.Name)
+			c.w.WriteString(`, `)
+			c.renderGeo(ob.Col, ob.Geo)
+			c.w.WriteString(`)`)
+		} else if ob.Var != "" {
 			switch c.ct {
 			case "mysql":
 				c.renderOrderByList(ob)
@@ -659,21 +714,24 @@ func (c *compilerContext) renderOrderBy(sel *qcode.Select) {
 		if ob.KeyVar != "" && ob.Key != "" {
 			c.w.WriteString(` END `)
 		}
-
-		switch ob.Order {
-		case qcode.OrderAsc:
-			c.w.WriteString(` ASC`)
-		case qcode.OrderDesc:
-			c.w.WriteString(` DESC`)
-		case qcode.OrderAscNullsFirst:
-			c.w.WriteString(` ASC NULLS FIRST`)
-		case qcode.OrderDescNullsFirst:
-			c.w.WriteString(` DESC NULLLS FIRST`)
-		case qcode.OrderAscNullsLast:
-			c.w.WriteString(` ASC NULLS LAST`)
-		case qcode.OrderDescNullsLast:
-			c.w.WriteString(` DESC NULLS LAST`)
-		}
+		c.renderOrder(ob.Order)
+	}
+}
+
+func (c *compilerContext) renderOrder(order qcode.Order) {
+	switch order {
+	case qcode.OrderAsc:
+		c.w.WriteString(` ASC`)
+	case qcode.OrderDesc:
+		c.w.WriteString(` DESC`)
+	case qcode.OrderAscNullsFirst:
+		c.w.WriteString(` ASC NULLS FIRST`)
+	case qcode.OrderDescNullsFirst:
+		c.w.WriteString(` DESC NULLLS FIRST`)
+	case qcode.OrderAscNullsLast:
+		c.w.WriteString(` ASC NULLS LAST`)
+	case qcode.OrderDescNullsLast:
+		c.w.WriteString(` DESC NULLS LAST`)
 	}
 }
 
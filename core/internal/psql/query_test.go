@@ -3,6 +3,7 @@ package psql_test
 import (
 	"bytes"
 	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -192,6 +193,47 @@ func searchQuery(t *testing.T) {
 	compileGQLToPSQL(t, gql, nil, "admin")
 }
 
+func searchOptions(t *testing.T) {
+	gql := `query {
+		products(
+			search: { query: $query, mode: phrase, config: "english", weights: { a: 1.0, b: 0.5 } }
+			order_by: { search_rank: desc }
+		) {
+			id
+			search_rank
+			search_headline_description
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "admin")
+}
+
+func searchInvalid(t *testing.T) {
+	gql := `query {
+		products(search: { query: $query, mode: fuzzy }) {
+			id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+
+	gql = `query {
+		products(search: { query: $query, config: "english'; --" }) {
+			id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+
+	gql = `query {
+		products(order_by: { search_rank: desc }) {
+			id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+}
+
 func oneToMany(t *testing.T) {
 	gql := `query {
 		users {
@@ -318,6 +360,368 @@ func aggFunctionWithFilter(t *testing.T) {
 	compileGQLToPSQL(t, gql, nil, "user")
 }
 
+func windowFunctions(t *testing.T) {
+	gql := `query {
+		products(order_by: { price: desc }) {
+			id
+			name
+			price
+			row_number(partition_by: [user_id], order_by: { price: desc })
+			lag_price(order_by: { id: asc }, offset: 2)
+			running_sum_price(partition_by: user_id, order_by: { id: asc })
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "admin")
+}
+
+func windowFunctionNeedsOrder(t *testing.T) {
+	gql := `query {
+		products {
+			id
+			running_sum_price
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+}
+
+func windowFunctionBlockedByCol(t *testing.T) {
+	gql := `query {
+		products {
+			id
+			rank(order_by: { price: desc })
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "anon")
+}
+
+func windowFunctionWithAgg(t *testing.T) {
+	gql := `query {
+		products {
+			count_id
+			rank(order_by: { id: desc })
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+}
+
+func timeBucket(t *testing.T) {
+	gql := `query {
+		products(where: { price: { gt: 10 } }) {
+			hour: bucket(column: created_at, interval: "hour")
+			count_id
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "admin")
+}
+
+func timeBucketFixedInterval(t *testing.T) {
+	gql := `query {
+		products {
+			bucket(column: created_at, interval: "15 minutes")
+			count_id
+			max_price
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "admin")
+}
+
+func timeBucketInvalid(t *testing.T) {
+	gql := `query {
+		products {
+			bucket(column: name, interval: "3 months")
+			count_id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+}
+
+func searchLiteral(t *testing.T) {
+	gql := `query {
+		products(search: "a'b") {
+			id
+			search_rank
+		}
+	}`
+
+	qc, err := qcompile.Compile([]byte(gql), nil, "admin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md, sql, err := pcompile.CompileEx(qc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(sql), `a'b`) {
+		t.Fatalf("expected the search value to be bound as a param: %s", sql)
+	}
+	for _, p := range md.Params() {
+		if !p.HasValue || p.Value != `a'b` {
+			t.Fatalf("unexpected params: %+v", md.Params())
+		}
+	}
+}
+
+func spatialWhere(t *testing.T) {
+	gql := `query {
+		stores(where: {
+			location: { st_dwithin: { point: [-122.4194, 37.7749], distance: $radius } }
+			delivery_area: { st_intersects: $area }
+		}) {
+			id
+			name
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "admin")
+}
+
+func spatialBBox(t *testing.T) {
+	gql := `query {
+		stores(where: { location: { st_bbox: [-123.0, 37.0, -122.0, 38.0] } }) {
+			id
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "admin")
+}
+
+func spatialDistance(t *testing.T) {
+	gql := `query {
+		stores(order_by: { distance_location: { point: [-122.4194, 37.7749] } }, limit: 5) {
+			id
+			name
+			distance_location(point: [-122.4194, 37.7749])
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "admin")
+}
+
+func spatialInvalid(t *testing.T) {
+	gql := `query {
+		stores(where: { name: { st_intersects: $area } }) {
+			id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+
+	gql = `query {
+		stores(where: { location: { st_dwithin: { point: [-122.4194, 37.7749] } } }) {
+			id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+}
+
+func spatialLiteral(t *testing.T) {
+	gql := `query {
+		stores(where: {
+			delivery_area: { st_intersects: "{ \"type\": \"Point\", \"coordinates\": [-122.4, 37.7] }" }
+		}) {
+			id
+		}
+	}`
+
+	qc, err := qcompile.Compile([]byte(gql), nil, "admin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md, sql, err := pcompile.CompileEx(qc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(sql), `coordinates`) {
+		t.Fatalf("expected the geojson to be bound as a param: %s", sql)
+	}
+
+	params := md.Params()
+	if len(params) != 1 || !params[0].HasValue ||
+		params[0].Value != `{ "type": "Point", "coordinates": [-122.4, 37.7] }` {
+		t.Fatalf("unexpected params: %+v", params)
+	}
+
+	gql = `query {
+		stores(where: { delivery_area: { st_intersects: "x') OR 1=1 --" } }) {
+			id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+}
+
+func vectorNearest(t *testing.T) {
+	gql := `query {
+		documents(nearest: { column: embedding, vector: $embedding, metric: cosine }, limit: 5) {
+			id
+			title
+			distance_embedding(vector: $embedding, metric: cosine)
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "user")
+}
+
+func vectorInvalid(t *testing.T) {
+	gql := `query {
+		documents(nearest: { column: title, vector: $embedding }) {
+			id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "user")
+
+	gql = `query {
+		documents(nearest: { column: embedding, vector: "[1, 2, 3]" }) {
+			id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "user")
+
+	gql = `query {
+		documents {
+			id
+			distance_embedding
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "user")
+}
+
+func deferAndStream(t *testing.T) {
+	gql := `
+	fragment productDetails on product {
+		price
+		tags
+	}
+
+	query {
+		products(limit: 5, order_by: { id: asc }) {
+			id
+			name
+			...productDetails @defer(label: "details")
+			customers @stream(initialCount: 1) {
+				id
+				vip
+			}
+			owner: user @defer {
+				id
+				email
+			}
+		}
+	}`
+
+	qc, err := qcompile.Compile([]byte(gql), nil, "admin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(qc.Defers) != 3 {
+		t.Fatalf("expected 3 deferred parts got %d", len(qc.Defers))
+	}
+
+	init, parts := qc.Incremental()
+	for _, q := range append(parts, init) {
+		if _, _, err := pcompile.CompileEx(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, sql, err := pcompile.CompileEx(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(sql), `LIMIT 19 OFFSET 1`) {
+		t.Fatalf("expected the streamed part to skip the initial items: %s", sql)
+	}
+}
+
+func deferOrder(t *testing.T) {
+	gql := `query {
+		products(limit: 5) {
+			id
+			customers @stream(initialCount: 1) {
+				id
+			}
+			user @defer {
+				email
+			}
+		}
+	}`
+
+	qc, err := qcompile.Compile([]byte(gql), nil, "admin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	init, parts := qc.Incremental()
+	for _, q := range append(parts, init) {
+		_, sql, err := pcompile.CompileEx(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(sql), `ORDER BY "products"."id"`) {
+			t.Fatalf("expected the products to be ordered by id: %s", sql)
+		}
+	}
+
+	for _, q := range parts {
+		if !q.Defer.Stream {
+			continue
+		}
+		_, sql, err := pcompile.CompileEx(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(sql), `ORDER BY "customers"."id"`) {
+			t.Fatalf("expected the streamed customers to be ordered by id: %s", sql)
+		}
+	}
+}
+
+func deferInvalid(t *testing.T) {
+	gql := `query {
+		products {
+			id
+			user @stream {
+				id
+			}
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+
+	gql = `query {
+		products(limit: $limit) @stream(initialCount: 2) {
+			id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+
+	gql = `mutation {
+		products(insert: $data) {
+			id
+			user @defer {
+				id
+			}
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+}
+
 func syntheticTables(t *testing.T) {
 	gql := `query {
 		me {
@@ -679,6 +1083,9 @@ func TestCompileQuery(t *testing.T) {
 	t.Run("withAlternateName", withAlternateName)
 	t.Run("fetchByID", fetchByID)
 	t.Run("searchQuery", searchQuery)
+	t.Run("searchOptions", searchOptions)
+	t.Run("searchInvalid", searchInvalid)
+	t.Run("searchLiteral", searchLiteral)
 	t.Run("oneToMany", oneToMany)
 	t.Run("oneToManyReverse", oneToManyReverse)
 	t.Run("oneToManyArray", oneToManyArray)
@@ -688,6 +1095,23 @@ func TestCompileQuery(t *testing.T) {
 	t.Run("aggFunctionBlockedByCol", aggFunctionBlockedByCol)
 	t.Run("aggFunctionDisabled", aggFunctionDisabled)
 	t.Run("aggFunctionWithFilter", aggFunctionWithFilter)
+	t.Run("windowFunctions", windowFunctions)
+	t.Run("windowFunctionNeedsOrder", windowFunctionNeedsOrder)
+	t.Run("windowFunctionBlockedByCol", windowFunctionBlockedByCol)
+	t.Run("windowFunctionWithAgg", windowFunctionWithAgg)
+	t.Run("timeBucket", timeBucket)
+	t.Run("timeBucketFixedInterval", timeBucketFixedInterval)
+	t.Run("timeBucketInvalid", timeBucketInvalid)
+	t.Run("spatialWhere", spatialWhere)
+	t.Run("spatialBBox", spatialBBox)
+	t.Run("spatialDistance", spatialDistance)
+	t.Run("spatialInvalid", spatialInvalid)
+	t.Run("spatialLiteral", spatialLiteral)
+	t.Run("vectorNearest", vectorNearest)
+	t.Run("vectorInvalid", vectorInvalid)
+	t.Run("deferAndStream", deferAndStream)
+	t.Run("deferOrder", deferOrder)
+	t.Run("deferInvalid", deferInvalid)
 	t.Run("syntheticTables", syntheticTables)
 	t.Run("queryWithVariables", queryWithVariables)
 	t.Run("withWhereOnRelations", withWhereOnRelations)
@@ -0,0 +1,124 @@
+package psql
+
+import (
+	"github.com/dosco/graphjin/core/v3/internal/qcode"
+	"github.com/dosco/graphjin/core/v3/internal/sdata"
+)
+
+func (c *compilerContext) renderFunctionSearchRank(sel *qcode.Select, f qcode.Field) {
+	if c.ct == "mysql" {
+		// the match expression returns the relevance in a select
+		c.renderMysqlMatch(sel.Ti, sel.Table, sel.Search)
+		return
+	}
+
+	c.w.WriteString(`ts_rank(`)
+	if len(sel.Search.Weights) != 0 {
+		c.w.WriteString(`CAST('{`)
+		for i, w := range sel.Search.Weights {
+			if i != 0 {
+				c.w.WriteString(`, `)
+			}
+			c.w.WriteString(w)
+		}
+		c.w.WriteString(`}' AS float4[]), `)
+	}
+	for i, col := range sel.Ti.FullText {
+		if i != 0 {
+			c.w.WriteString(` || `)
+		}
+		c.colWithTable(sel.Table, col.Name)
+	}
+	c.w.WriteString(`, `)
+	c.renderTsQuery(sel.Search)
+	c.w.WriteString(`)`)
+}
+
+func (c *compilerContext) renderFunctionSearchHeadline(sel *qcode.Select, f qcode.Field) {
+	col := f.Args[0].Col
+
+	// mysql has no headline support so we return the column as is
+	if c.ct == "mysql" {
+		c.colWithTable(sel.Table, col.Name)
+		return
+	}
+
+	c.w.WriteString(`ts_headline(`)
+	if sel.Search.Config != "" {
+		c.renderSearchConfig(sel.Search)
+		c.w.WriteString(`, `)
+	}
+	c.colWithTable(sel.Table, col.Name)
+	c.w.WriteString(`, `)
+	c.renderTsQuery(sel.Search)
+	c.w.WriteString(`)`)
+}
+
+// renderTsQuery renders the postgres tsquery for the search mode
+func (c *compilerContext) renderTsQuery(s *qcode.Search) {
+	switch {
+	case s.Mode == qcode.SearchPhrase:
+		c.w.WriteString(`phraseto_tsquery(`)
+	case s.Mode == qcode.SearchPlain:
+		c.w.WriteString(`plainto_tsquery(`)
+	case s.Mode == qcode.SearchRaw:
+		c.w.WriteString(`to_tsquery(`)
+	case c.cv >= 110000:
+		c.w.WriteString(`websearch_to_tsquery(`)
+	case s.Mode == qcode.SearchWebsearch:
+		// websearch_to_tsquery was only added in postgres 11
+		c.w.WriteString(`plainto_tsquery(`)
+	default:
+		c.w.WriteString(`to_tsquery(`)
+	}
+
+	if s.Config != "" {
+		c.renderSearchConfig(s)
+		c.w.WriteString(`, `)
+	}
+	c.renderSearchVal(s)
+	c.w.WriteString(`)`)
+}
+
+func (c *compilerContext) renderSearchConfig(s *qcode.Search) {
+	if s.ConfigType == qcode.ValVar {
+		c.w.WriteString(`CAST(`)
+		c.renderParam(Param{Name: s.Config, Type: "text"})
+		c.w.WriteString(` AS regconfig)`)
+	} else {
+		c.squoted(s.Config)
+	}
+}
+
+func (c *compilerContext) renderSearchVal(s *qcode.Search) {
+	if s.ValType == qcode.ValVar {
+		c.renderParam(Param{Name: s.Val, Type: "text"})
+	} else {
+		c.renderValParam("text", s.Val)
+	}
+}
+
+// renderMysqlMatch renders MATCH (name) AGAINST ('phone' IN BOOLEAN MODE)
+func (c *compilerContext) renderMysqlMatch(ti sdata.DBTable, table string, s *qcode.Search) {
+	c.w.WriteString(`MATCH(`)
+	for i, col := range ti.FullText {
+		if i != 0 {
+			c.w.WriteString(`, `)
+		}
+		c.colWithTable(table, col.Name)
+	}
+	c.w.WriteString(`) AGAINST (`)
+
+	switch s.Mode {
+	case qcode.SearchPhrase:
+		c.w.WriteString(`CONCAT('"', `)
+		c.renderSearchVal(s)
+		c.w.WriteString(`, '"') IN BOOLEAN MODE)`)
+	case qcode.SearchWebsearch, qcode.SearchRaw:
+		c.renderSearchVal(s)
+		c.w.WriteString(` IN BOOLEAN MODE)`)
+	default:
+		c.renderSearchVal(s)
+		c.w.WriteString(` IN NATURAL LANGUAGE MODE)`)
+	}
+}
@@ -0,0 +1,26 @@
+package psql
+
+import (
+	"github.com/dosco/graphjin/core/v3/internal/qcode"
+	"github.com/dosco/graphjin/core/v3/internal/sdata"
+)
+
+// renderVectorDistance renders the pgvector distance operator
+// between a column and a query vector
+func (c *compilerContext) renderVectorDistance(col sdata.DBColumn, v *qcode.Vector) {
+	c.w.WriteString(`(`)
+	c.colWithTable(col.Table, col.Name)
+
+	switch v.Metric {
+	case qcode.VectorCosine:
+		c.w.WriteString(` <=> `)
+	case qcode.VectorInnerProduct:
+		c.w.WriteString(` <#> `)
+	default:
+		c.w.WriteString(` <-> `)
+	}
+
+	c.w.WriteString(`CAST(`)
+	c.renderParam(Param{Name: v.Val, Type: "vector"})
+	c.w.WriteString(` AS vector))`)
+}
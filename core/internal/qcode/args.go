@@ -48,6 +48,9 @@ func (co *Compiler) compileSelectArgs(sel *Select, args []graph.Arg, role string
 		case "find":
 			err = co.compileArgFind(sel, a)
 
+		case "nearest":
+			err = co.compileArgNearest(sel, a)
+
 		case "args":
 			err = co.compileArgArgs(sel, a)
 
@@ -134,15 +137,16 @@ func (co *Compiler) compileArgSearch(sel *Select, arg graph.Arg) (err error) {
 			return fmt.Errorf("no tsvector column defined on table '%s'", sel.Table)
 		}
 	}
-	if err = validateArg(arg, graph.NodeStr, graph.NodeVar); err != nil {
+	if sel.Search, err = co.compileSearch(arg.Val); err != nil {
 		return
 	}
 
 	ex := newExpOp(OpTsQuery)
-	ex.Right.ValType = ValVar
-	ex.Right.Val = arg.Val.Val
+	ex.Right.ValType = sel.Search.ValType
+	ex.Right.Val = sel.Search.Val
+	ex.Search = sel.Search
 
-	sel.addIArg(Arg{Name: arg.Name, Val: arg.Val.Val})
+	sel.addIArg(Arg{Name: arg.Name, Val: sel.Search.Val})
 	addAndFilter(&sel.Where, ex)
 	return nil
 }
@@ -277,6 +281,7 @@ func (co *Compiler) compileArgLimit(sel *Select, arg graph.Arg) (err error) {
 			return
 		}
 		sel.Paging.Limit = int32(n)
+		sel.Paging.DefaultLimit = false
 
 	case graph.NodeVar:
 		if co.s.DBType() == "mysql" {
@@ -354,6 +359,27 @@ func (co *Compiler) compileFieldArgs(sel *Select, f *Field, args []graph.Arg, ro
 		case "skipIf", "skip_if":
 			err = co.compileArgSkipIncludeIf(true, sel, f, a, role)
 
+		case "partitionBy", "partition_by", "partition":
+			err = co.compileWindowArgPartition(sel, f, a)
+
+		case "orderBy", "order_by", "order":
+			err = co.compileWindowArgOrderBy(sel, f, a)
+
+		case "offset":
+			err = co.compileWindowArgOffset(f, a)
+
+		case "column":
+			err = co.compileBucketArgColumn(sel, f, a)
+
+		case "interval":
+			err = co.compileBucketArgInterval(f, a)
+
+		case "point", "geometry":
+			err = co.compileDistanceArg(f, a)
+
+		case "vector", "metric":
+			err = co.compileVectorArg(f, a)
+
 		default:
 			err = unknownArg(a)
 		}
@@ -384,3 +410,74 @@ func (co *Compiler) compileFuncArgArgs(sel *Select, f *Field, arg graph.Arg) (er
 	f.Args = args
 	return
 }
+
+func (co *Compiler) compileWindowArgPartition(sel *Select, f *Field, arg graph.Arg) (err error) {
+	if f.Window == nil {
+		return unknownArg(arg)
+	}
+	if err = validateArg(arg,
+		graph.NodeList, graph.NodeLabel,
+		graph.NodeList, graph.NodeStr,
+		graph.NodeStr, graph.NodeLabel); err != nil {
+		return
+	}
+
+	node := arg.Val
+
+	if node.Type == graph.NodeStr || node.Type == graph.NodeLabel {
+		var col sdata.DBColumn
+		if col, err = sel.Ti.GetColumn(co.ParseName(node.Val)); err != nil {
+			return
+		}
+		f.Window.Partition = append(f.Window.Partition, col)
+	}
+
+	for _, cn := range node.Children {
+		var col sdata.DBColumn
+		if col, err = sel.Ti.GetColumn(co.ParseName(cn.Val)); err != nil {
+			return
+		}
+		f.Window.Partition = append(f.Window.Partition, col)
+	}
+	return
+}
+
+func (co *Compiler) compileWindowArgOrderBy(sel *Select, f *Field, arg graph.Arg) (err error) {
+	if f.Window == nil {
+		return unknownArg(arg)
+	}
+	if err = validateArg(arg, graph.NodeObj); err != nil {
+		return
+	}
+
+	for _, cn := range arg.Val.Children {
+		var ob OrderBy
+		if cn.Type != graph.NodeStr && cn.Type != graph.NodeLabel {
+			return fmt.Errorf("argument '%s', expecting a string", cn.Name)
+		}
+		if ob.Order, err = toOrder(cn.Val); err != nil {
+			return fmt.Errorf("argument '%s', %w", cn.Name, err)
+		}
+		if err = co.setOrderByColName(sel.Ti, &ob, cn); err != nil {
+			return
+		}
+		f.Window.OrderBy = append(f.Window.OrderBy, ob)
+	}
+	return
+}
+
+func (co *Compiler) compileWindowArgOffset(f *Field, arg graph.Arg) (err error) {
+	if f.Window == nil || (f.Func.Name != "lag" && f.Func.Name != "lead") {
+		return unknownArg(arg)
+	}
+	if err = validateArg(arg, graph.NodeNum); err != nil {
+		return
+	}
+
+	var n int64
+	if n, err = strconv.ParseInt(arg.Val.Val, 10, 32); err != nil {
+		return
+	}
+	f.Window.Offset = int32(n)
+	return
+}
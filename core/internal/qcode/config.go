@@ -32,12 +32,14 @@ type QueryConfig struct {
 	Columns          []string
 	DisableFunctions bool
 	Block            bool
+	Scopes           []string
 }
 
 type InsertConfig struct {
 	Columns []string
 	Presets map[string]string
 	Block   bool
+	Scopes  []string
 }
 
 type UpdateConfig struct {
@@ -45,6 +47,7 @@ type UpdateConfig struct {
 	Columns []string
 	Presets map[string]string
 	Block   bool
+	Scopes  []string
 }
 
 type UpsertConfig struct {
@@ -52,12 +55,14 @@ type UpsertConfig struct {
 	Columns []string
 	Presets map[string]string
 	Block   bool
+	Scopes  []string
 }
 
 type DeleteConfig struct {
 	Filters []string
 	Columns []string
 	Block   bool
+	Scopes  []string
 }
 
 type trval struct {
@@ -70,12 +75,14 @@ type trval struct {
 		cols    map[string]struct{}
 		disable struct{ funcs bool }
 		block   bool
+		scopes  []string
 	}
 
 	insert struct {
 		cols    map[string]struct{}
 		presets map[string]string
 		block   bool
+		scopes  []string
 	}
 
 	update struct {
@@ -84,6 +91,7 @@ type trval struct {
 		cols    map[string]struct{}
 		presets map[string]string
 		block   bool
+		scopes  []string
 	}
 
 	upsert struct {
@@ -92,13 +100,15 @@ type trval struct {
 		cols    map[string]struct{}
 		presets map[string]string
 		block   bool
+		scopes  []string
 	}
 
 	delete struct {
-		fil   *Exp
-		filNU bool
-		cols  map[string]struct{}
-		block bool
+		fil    *Exp
+		filNU  bool
+		cols   map[string]struct{}
+		block  bool
+		scopes []string
 	}
 }
 
@@ -124,11 +134,13 @@ func (co *Compiler) AddRole(role, schema, table string, trc TRConfig) error {
 	trv.query.cols = makeSet(trc.Query.Columns)
 	trv.query.disable.funcs = trc.Query.DisableFunctions
 	trv.query.block = trc.Query.Block
+	trv.query.scopes = trc.Query.Scopes
 
 	// insert config
 	trv.insert.cols = makeSet(trc.Insert.Columns)
 	trv.insert.presets = trc.Insert.Presets
 	trv.insert.block = trc.Insert.Block
+	trv.insert.scopes = trc.Insert.Scopes
 
 	// update config
 	trv.update.fil, trv.update.filNU, err = compileFilter(co.s, ti, trc.Update.Filters, false)
@@ -138,6 +150,7 @@ func (co *Compiler) AddRole(role, schema, table string, trc TRConfig) error {
 	trv.update.cols = makeSet(trc.Update.Columns)
 	trv.update.presets = trc.Update.Presets
 	trv.update.block = trc.Update.Block
+	trv.update.scopes = trc.Update.Scopes
 
 	// upsert config
 	trv.upsert.fil, trv.upsert.filNU, err = compileFilter(co.s, ti, trc.Upsert.Filters, false)
@@ -147,6 +160,7 @@ func (co *Compiler) AddRole(role, schema, table string, trc TRConfig) error {
 	trv.upsert.cols = makeSet(trc.Upsert.Columns)
 	trv.upsert.presets = trc.Upsert.Presets
 	trv.upsert.block = trc.Upsert.Block
+	trv.upsert.scopes = trc.Upsert.Scopes
 
 	// delete config
 	trv.delete.fil, trv.delete.filNU, err = compileFilter(co.s, ti, trc.Delete.Filters, false)
@@ -155,6 +169,7 @@ func (co *Compiler) AddRole(role, schema, table string, trc TRConfig) error {
 	}
 	trv.delete.cols = makeSet(trc.Delete.Columns)
 	trv.delete.block = trc.Delete.Block
+	trv.delete.scopes = trc.Delete.Scopes
 
 	if schema == "" {
 		schema = co.s.DBSchema()
@@ -247,6 +262,22 @@ func (trv *trval) isBlocked(qt QType) bool {
 	return false
 }
 
+func (trv *trval) scopes(qt QType) []string {
+	switch qt {
+	case QTQuery:
+		return trv.query.scopes
+	case QTInsert:
+		return trv.insert.scopes
+	case QTUpdate:
+		return trv.update.scopes
+	case QTUpsert:
+		return trv.upsert.scopes
+	case QTDelete:
+		return trv.delete.scopes
+	}
+	return nil
+}
+
 func (trv *trval) isFuncsBlocked() bool {
 	return trv.query.disable.funcs
 }
@@ -0,0 +1,262 @@
+package qcode
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dosco/graphjin/core/v3/internal/graph"
+)
+
+// Defer is a part of the query marked with the @defer or @stream directive,
+// these parts are fetched using a separate statement and delivered
+// after the initial result
+type Defer struct {
+	Label string
+	// selector holding the deferred fields or -1 for the root
+	ParentID     int32
+	Stream       bool
+	InitialCount int32
+	// the selector being streamed
+	SelID int32
+}
+
+// compileDirectiveDefer compiles the @defer and @stream directives
+// @defer(label: "details", if: true)
+// @stream(label: "posts", initialCount: 2)
+func (co *Compiler) compileDirectiveDefer(qc *QCode,
+	sel *Select, f *Field, d graph.Directive, stream bool,
+) (err error) {
+	isSel := (f == &sel.Field)
+
+	// nested parts are delivered along with the part they are in
+	if (!isSel && sel.Defer != 0) || qc.isDeferred(sel.ParentID) {
+		return
+	}
+
+	dv := Defer{ParentID: sel.ID, Stream: stream, SelID: -1}
+	if isSel {
+		dv.ParentID = sel.ParentID
+	}
+	if stream {
+		dv.SelID = sel.ID
+	}
+
+	for _, arg := range d.Args {
+		switch arg.Name {
+		case "label":
+			if err = validateArg(arg, graph.NodeStr); err != nil {
+				return
+			}
+			dv.Label = arg.Val.Val
+
+		case "if":
+			if err = validateArg(arg, graph.NodeBool); err != nil {
+				return
+			}
+			if arg.Val.Val == "false" {
+				return
+			}
+
+		case "initialCount", "initial_count":
+			if !stream {
+				return unknownArg(arg)
+			}
+			if err = validateArg(arg, graph.NodeNum); err != nil {
+				return
+			}
+			var n int
+			if n, err = strconv.Atoi(arg.Val.Val); err != nil || n < 0 {
+				return fmt.Errorf("'%s' must be zero or more", arg.Name)
+			}
+			dv.InitialCount = int32(n)
+
+		default:
+			return unknownArg(arg)
+		}
+	}
+
+	// fields deferred together under the same selector are fetched together
+	if !stream {
+		for i, v := range qc.Defers {
+			if !v.Stream && v.ParentID == dv.ParentID && v.Label == dv.Label {
+				f.Defer = int32(i + 1)
+				return
+			}
+		}
+	}
+
+	qc.Defers = append(qc.Defers, dv)
+	f.Defer = int32(len(qc.Defers))
+	return
+}
+
+// isDeferred returns true if the selector or any of its
+// parents is deferred
+func (qc *QCode) isDeferred(id int32) bool {
+	for id != -1 {
+		sel := &qc.Selects[id]
+		if sel.Defer != 0 {
+			return true
+		}
+		id = sel.ParentID
+	}
+	return false
+}
+
+func validateDefer(qc *QCode, sel *Select) error {
+	if sel.Defer == 0 {
+		return nil
+	}
+	d := qc.Defers[sel.Defer-1]
+
+	name := "@defer"
+	if d.Stream {
+		name = "@stream"
+	}
+
+	switch {
+	case sel.Type == SelTypeUnion || sel.Type == SelTypeMember:
+		return fmt.Errorf("%s: not supported on union types: %s", name, sel.FieldName)
+	case sel.SkipRender == SkipTypeRemote:
+		return fmt.Errorf("%s: not supported on remote joins: %s", name, sel.FieldName)
+	case sel.Paging.Cursor:
+		return fmt.Errorf("%s: not supported with cursor pagination: %s", name, sel.FieldName)
+	}
+
+	if !d.Stream {
+		return nil
+	}
+
+	switch {
+	case sel.Singular:
+		return fmt.Errorf("%s: '%s' is not a list", name, sel.FieldName)
+	case sel.Paging.LimitVar != "" || sel.Paging.OffsetVar != "":
+		return fmt.Errorf("%s: variable limit or offset not supported: %s", name, sel.FieldName)
+	}
+	return nil
+}
+
+// orderDeferred orders the lists on the path to the deferred parts by their
+// primary key so the initial result and the parts return the items in the
+// same order and the paths of the parts point to the right items
+func (co *Compiler) orderDeferred(qc *QCode) error {
+	for _, d := range qc.Defers {
+		id, name := d.ParentID, "@defer"
+		if d.Stream {
+			id, name = d.SelID, "@stream"
+		}
+		for ; id != -1; id = qc.Selects[id].ParentID {
+			sel := &qc.Selects[id]
+			if sel.Singular {
+				continue
+			}
+			if err := co.orderByIDCol(sel); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Incremental splits a query using the @defer or @stream directives into
+// the query for the initial result and a query for each of the deferred parts
+func (qc *QCode) Incremental() (init *QCode, parts []*QCode) {
+	init = qc.copy()
+
+	for i := range init.Selects {
+		sel := &init.Selects[i]
+		sel.Fields = withoutDeferred(sel.Fields)
+
+		if sel.Defer == 0 {
+			continue
+		}
+
+		d := qc.Defers[sel.Defer-1]
+		if !d.Stream {
+			sel.SkipRender = SkipTypeDrop
+			continue
+		}
+
+		if sel.Paging.NoLimit || sel.Paging.Limit > d.InitialCount {
+			sel.Paging.NoLimit = false
+			sel.Paging.Limit = d.InitialCount
+		}
+	}
+
+	parts = make([]*QCode, len(qc.Defers))
+	for i := range qc.Defers {
+		parts[i] = qc.deferPart(int32(i + 1))
+	}
+	return
+}
+
+// deferPart returns the query for a deferred part, it only includes the
+// deferred fields and the selectors on the path to them
+func (qc *QCode) deferPart(n int32) *QCode {
+	p := qc.copy()
+	p.Defer = &qc.Defers[n-1]
+	p.Typename = false
+	p.Remotes = 0
+
+	d := p.Defer
+	path := make(map[int32]struct{})
+
+	for id := d.ParentID; id != -1; id = qc.Selects[id].ParentID {
+		path[id] = struct{}{}
+	}
+
+	for i := range p.Selects {
+		sel := &p.Selects[i]
+		_, onPath := path[sel.ID]
+		_, parentOnPath := path[sel.ParentID]
+
+		switch {
+		case onPath:
+			fields := sel.Fields[:0]
+			for _, f := range sel.Fields {
+				if sel.ID == d.ParentID && f.Defer == n {
+					fields = append(fields, f)
+				}
+			}
+			sel.Fields = fields
+			sel.Typename = false
+
+		case sel.Defer == n:
+			// skip the items already part of the initial result
+			if d.Stream {
+				sel.Paging.Offset += d.InitialCount
+				if sel.Paging.Limit > d.InitialCount {
+					sel.Paging.Limit -= d.InitialCount
+				} else {
+					sel.Paging.Limit = 0
+				}
+			}
+
+		case sel.ParentID == -1 || parentOnPath:
+			sel.SkipRender = SkipTypeDrop
+		}
+	}
+	return p
+}
+
+func (qc *QCode) copy() *QCode {
+	c := *qc
+	c.Selects = make([]Select, len(qc.Selects))
+	copy(c.Selects, qc.Selects)
+
+	for i := range c.Selects {
+		sel := &c.Selects[i]
+		sel.Fields = append([]Field(nil), sel.Fields...)
+	}
+	return &c
+}
+
+func withoutDeferred(fields []Field) []Field {
+	fl := fields[:0]
+	for _, f := range fields {
+		if f.Defer == 0 {
+			fl = append(fl, f)
+		}
+	}
+	return fl
+}
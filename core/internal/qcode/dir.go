@@ -61,6 +61,12 @@ func (co *Compiler) compileSelectorDirectives(qc *QCode,
 			sel.Singular = true
 			sel.Paging.Limit = 1
 
+		case "defer":
+			err = co.compileDirectiveDefer(qc, sel, &sel.Field, d, false)
+
+		case "stream":
+			err = co.compileDirectiveDefer(qc, sel, &sel.Field, d, true)
+
 		default:
 			err = fmt.Errorf("no such selector directive: %s", d.Name)
 		}
@@ -72,7 +78,7 @@ func (co *Compiler) compileSelectorDirectives(qc *QCode,
 	return
 }
 
-func (co *Compiler) compileFieldDirectives(sel *Select,
+func (co *Compiler) compileFieldDirectives(qc *QCode, sel *Select,
 	f *Field, dirs []graph.Directive, role string,
 ) (err error) {
 	for _, d := range dirs {
@@ -89,6 +95,9 @@ func (co *Compiler) compileFieldDirectives(sel *Select,
 		case "skip":
 			err = co.compileDirectiveSkipInclude(true, sel, f, d, role)
 
+		case "defer":
+			err = co.compileDirectiveDefer(qc, sel, f, d, false)
+
 		default:
 			err = fmt.Errorf("unknown field directive: %s", d.Name)
 		}
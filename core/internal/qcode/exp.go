@@ -185,6 +185,11 @@ func (ast *aexpst) parseNode(av aexp, node *graph.Node, selID int32) (*Exp, erro
 			ex.Right.Path = append(ex.Right.Path, vn.Name)
 		}
 
+		// spatial operators keep their values in ex.Geo
+		if ex.Geo != nil {
+			break
+		}
+
 		if ex.Right.ValType, err = getExpType(vn); err != nil {
 			return nil, err
 		}
@@ -381,7 +386,7 @@ func (ast *aexpst) processOpAndVal(av aexp, ex *Exp, node *graph.Node) (bool, er
 		ex.Op = OpDistinct
 		ex.Right.Val = node.Val
 	default:
-		return false, nil
+		return ast.processGeoOp(ex, node, name)
 	}
 
 	return true, nil
@@ -47,7 +47,7 @@ func (co *Compiler) compileChildColumns(
 	tr trval,
 	role string,
 ) (err error) {
-	var aggExists bool
+	var aggExists, windowExists bool
 	var id int32
 
 	for _, cid := range gf.Children {
@@ -102,12 +102,20 @@ func (co *Compiler) compileChildColumns(
 			field.Type = FieldTypeFunc
 			field.Func = fn.Func
 			field.Args = fn.Args
-			aggExists = fn.Agg
+			field.Window = fn.Window
+			field.Geo = fn.Geo
+			field.Vector = fn.Vector
+			if fn.Agg {
+				aggExists = true
+			}
+			if fn.Window != nil {
+				windowExists = true
+			}
 		default:
 			return fmt.Errorf("field '%s' is not a column or a function", name)
 		}
 
-		if err := co.compileFieldDirectives(sel, &field, f.Directives, role); err != nil {
+		if err := co.compileFieldDirectives(qc, sel, &field, f.Directives, role); err != nil {
 			return err
 		}
 
@@ -115,6 +123,18 @@ func (co *Compiler) compileChildColumns(
 			return err
 		}
 
+		if err := validateWindow(field); err != nil {
+			return err
+		}
+
+		if err := validateBucket(field); err != nil {
+			return err
+		}
+
+		if err := validateDistance(field); err != nil {
+			return err
+		}
+
 		if field.Col.Blocked {
 			return fmt.Errorf("column: '%s.%s.%s' blocked",
 				field.Col.Schema,
@@ -137,6 +157,10 @@ func (co *Compiler) compileChildColumns(
 		id++
 	}
 
+	if aggExists && windowExists {
+		return fmt.Errorf("window functions cannot be used with aggregate functions")
+	}
+
 	if aggExists {
 		sel.GroupCols = true
 	}
@@ -198,6 +222,10 @@ func parseArg(arg *graph.Node, f sdata.DBFunction, index int) (a Arg, err error)
 
 func (co *Compiler) addOrderByColumns(sel *Select) {
 	for _, ob := range sel.OrderBy {
+		// distances are computed from the table column itself
+		if ob.Geo != nil || ob.Vector != nil || ob.SearchRank {
+			continue
+		}
 		sel.addBaseCol(Column{Col: ob.Col})
 	}
 }
@@ -284,6 +312,11 @@ func validateSelector(qc *QCode, sel *Select, tr trval) error {
 			return err
 		}
 	}
+	for _, ob := range sel.OrderBy {
+		if (ob.Geo != nil || ob.Vector != nil) && !tr.columnAllowed(qc, ob.Col.Name) {
+			return validateErr(tr, ob.Col.Name, "db column blocked")
+		}
+	}
 	return nil
 }
 
@@ -300,11 +333,28 @@ func validateField(qc *QCode, f Field, tr trval) error {
 		if len(f.Args) != 0 && !tr.columnAllowed(qc, f.Args[0].Col.Name) {
 			return validateErr(tr, f.Args[0].Col.Name, "db column blocked")
 		}
+		if f.Window != nil {
+			return validateWindowColumns(qc, f.Window, tr)
+		}
 	}
 
 	return nil
 }
 
+func validateWindowColumns(qc *QCode, w *Window, tr trval) error {
+	for _, col := range w.Partition {
+		if !tr.columnAllowed(qc, col.Name) {
+			return validateErr(tr, col.Name, "db column blocked")
+		}
+	}
+	for _, ob := range w.OrderBy {
+		if !tr.columnAllowed(qc, ob.Col.Name) {
+			return validateErr(tr, ob.Col.Name, "db column blocked")
+		}
+	}
+	return nil
+}
+
 func validateErr(tr trval, name, msg string) error {
 	return fmt.Errorf("%s: %s (role: '%s')", msg, name, tr.role)
 }
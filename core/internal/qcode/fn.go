@@ -2,6 +2,7 @@ package qcode
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/dosco/graphjin/core/v3/internal/graph"
@@ -14,22 +15,39 @@ func (co *Compiler) isFunction(sel *Select, name string, f graph.Field) (
 	switch {
 	case name == "search_rank":
 		isFunc = true
-		if _, ok := sel.GetInternalArg("search"); !ok {
+		fn.Name = name
+		fn.Func = sdata.DBFunction{Name: name, Type: "real"}
+		if sel.Search == nil {
 			err = fmt.Errorf("search argument not found: %s", name)
 		}
 
 	case strings.HasPrefix(name, "search_headline_"):
 		isFunc = true
 		fn.Name = "search_headline"
+		fn.Func = sdata.DBFunction{Name: fn.Name, Type: "text"}
 		fn.Args = []Arg{{Type: ArgTypeCol}}
 		fn.Args[0].Col, err = sel.Ti.GetColumn(name[(len(fn.Name) + 1):])
 		if err != nil {
 			return
 		}
-		if _, ok := sel.GetInternalArg("search"); !ok {
+		if sel.Search == nil {
 			err = fmt.Errorf("no search defined: %s", name)
 		}
 
+	case name == "bucket":
+		isFunc = true
+		fn.Name = name
+		fn.Func = sdata.DBFunction{Name: name, Type: "timestamp"}
+		fn.Args = []Arg{{Type: ArgTypeCol}, {Type: ArgTypeVal, Name: "interval"}}
+
+	case isDistanceFunction(sel, name):
+		isFunc = true
+		fn, err = co.compileDistanceFunction(sel, name)
+
+	case isWindowFunction(sel, name):
+		isFunc = true
+		fn, err = co.compileWindowFunction(sel, name)
+
 	default:
 		var fi funcInfo
 		if fi, isFunc, err = co.isFunctionEx(sel, name, f); isFunc {
@@ -87,3 +105,198 @@ func (co *Compiler) isFunctionEx(sel *Select, name string, f graph.Field) (
 
 	return
 }
+
+var (
+	// window functions that take no column
+	windowFuncs = map[string]string{
+		"row_number":   "bigint",
+		"rank":         "bigint",
+		"dense_rank":   "bigint",
+		"percent_rank": "double precision",
+		"cume_dist":    "double precision",
+	}
+
+	// window functions applied to a column (eg. lag_price)
+	windowColFuncs = []string{"lag", "lead", "first_value", "last_value"}
+
+	// aggregates run over the window frame (eg. running_sum_price)
+	runningFuncs = []string{"sum", "avg", "count", "min", "max"}
+)
+
+const runningPrefix = "running_"
+
+// isWindowFunction returns true if the name is a window function, names
+// that only share a prefix with a window function (eg. rank_users) fall
+// through to the database functions unless the rest is a column.
+func isWindowFunction(sel *Select, name string) bool {
+	if _, ok := windowFuncs[name]; ok {
+		return true
+	}
+	fnName, colName, _ := parseWindowFunction(name)
+	if fnName == "" {
+		return false
+	}
+	_, err := sel.Ti.GetColumn(colName)
+	return err == nil
+}
+
+// parseWindowFunction splits a window function applied to a column
+// into the function and the column name
+func parseWindowFunction(name string) (fnName, colName string, running bool) {
+	if strings.HasPrefix(name, runningPrefix) {
+		n := name[len(runningPrefix):]
+		for _, v := range runningFuncs {
+			if strings.HasPrefix(n, (v + "_")) {
+				return v, n[(len(v) + 1):], true
+			}
+		}
+	}
+	for _, v := range windowColFuncs {
+		if strings.HasPrefix(name, (v + "_")) {
+			return v, name[(len(v) + 1):], false
+		}
+	}
+	return "", "", false
+}
+
+func (co *Compiler) compileWindowFunction(sel *Select, name string) (
+	fn Function, err error,
+) {
+	fn.Window = &Window{}
+
+	if ty, ok := windowFuncs[name]; ok {
+		fn.Name = name
+		fn.Func = sdata.DBFunction{Name: name, Type: ty}
+		return
+	}
+
+	var colName string
+	fn.Name, colName, fn.Window.Running = parseWindowFunction(name)
+
+	var col sdata.DBColumn
+	if col, err = sel.Ti.GetColumn(colName); err != nil {
+		return
+	}
+	fn.Func = sdata.DBFunction{Name: fn.Name, Type: col.Type}
+	if fn.Name == "count" {
+		fn.Func.Type = "bigint"
+	}
+	fn.Args = []Arg{{Type: ArgTypeCol, Col: col}}
+	return
+}
+
+func validateWindow(f Field) error {
+	if f.Window == nil {
+		return nil
+	}
+	if len(f.Window.OrderBy) != 0 {
+		return nil
+	}
+	switch {
+	case f.Window.Running, f.Func.Name == "lag", f.Func.Name == "lead":
+		return fmt.Errorf("window function '%s': argument 'order_by' required", f.FieldName)
+	}
+	return nil
+}
+
+// bucketUnits are the units a time bucket can be truncated to
+// along with their length in seconds, calendar units have no
+// fixed length and can only be used with a count of one.
+var bucketUnits = map[string]int64{
+	"second":  1,
+	"minute":  60,
+	"hour":    3600,
+	"day":     86400,
+	"week":    604800,
+	"month":   0,
+	"quarter": 0,
+	"year":    0,
+}
+
+func (co *Compiler) compileBucketArgColumn(sel *Select, f *Field, arg graph.Arg) (err error) {
+	if f.Func.Name != "bucket" {
+		return unknownArg(arg)
+	}
+	if err = validateArg(arg, graph.NodeLabel, graph.NodeStr); err != nil {
+		return
+	}
+
+	var col sdata.DBColumn
+	if col, err = sel.Ti.GetColumn(co.ParseName(arg.Val.Val)); err != nil {
+		return
+	}
+	if !strings.Contains(col.Type, "date") && !strings.Contains(col.Type, "time") {
+		return fmt.Errorf("column '%s' is not a date or time column", col.Name)
+	}
+	f.Args[0].Col = col
+	return
+}
+
+func (co *Compiler) compileBucketArgInterval(f *Field, arg graph.Arg) (err error) {
+	if f.Func.Name != "bucket" {
+		return unknownArg(arg)
+	}
+	if err = validateArg(arg, graph.NodeStr, graph.NodeLabel); err != nil {
+		return
+	}
+
+	a, err := parseBucketInterval(arg.Val.Val)
+	if err != nil {
+		return
+	}
+	f.Args[1] = a
+	return
+}
+
+// parseBucketInterval accepts a unit name (eg. hour) or a count and
+// a unit (eg. 15 minutes). Calendar units are truncated to while fixed
+// length intervals are converted to seconds.
+func parseBucketInterval(val string) (a Arg, err error) {
+	a.Type = ArgTypeVal
+
+	v := strings.Fields(strings.ToLower(val))
+	n := int64(1)
+
+	switch len(v) {
+	case 1:
+	case 2:
+		if n, err = strconv.ParseInt(v[0], 10, 32); err != nil || n < 1 {
+			err = fmt.Errorf("invalid interval '%s'", val)
+			return
+		}
+		v = v[1:]
+	default:
+		err = fmt.Errorf("invalid interval '%s'", val)
+		return
+	}
+
+	unit := strings.TrimSuffix(v[0], "s")
+	secs, ok := bucketUnits[unit]
+
+	switch {
+	case !ok:
+		err = fmt.Errorf("invalid interval unit '%s'", v[0])
+	case n == 1:
+		a.Name = "interval"
+		a.Val = unit
+	case secs == 0:
+		err = fmt.Errorf("interval unit '%s' can only be used with a count of one", unit)
+	default:
+		a.Name = "seconds"
+		a.Val = strconv.FormatInt(n*secs, 10)
+	}
+	return
+}
+
+func validateBucket(f Field) error {
+	if f.Type != FieldTypeFunc || f.Func.Name != "bucket" {
+		return nil
+	}
+	if f.Args[0].Col.Name == "" {
+		return fmt.Errorf("bucket '%s': argument 'column' required", f.FieldName)
+	}
+	if f.Args[1].Val == "" {
+		return fmt.Errorf("bucket '%s': argument 'interval' required", f.FieldName)
+	}
+	return nil
+}
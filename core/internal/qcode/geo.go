@@ -0,0 +1,269 @@
+package qcode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dosco/graphjin/core/v3/internal/graph"
+	"github.com/dosco/graphjin/core/v3/internal/sdata"
+)
+
+const distancePrefix = "distance_"
+
+// processGeoOp compiles the spatial operators
+// { location: { st_dwithin: { point: [lng, lat], distance: 1000 } } }
+func (ast *aexpst) processGeoOp(ex *Exp, node *graph.Node, name string) (bool, error) {
+	switch name {
+	case "st_dwithin", "stDWithin", "stDwithin":
+		ex.Op = OpStDWithin
+	case "st_intersects", "stIntersects":
+		ex.Op = OpStIntersects
+	case "st_contains", "stContains":
+		ex.Op = OpStContains
+	case "st_bbox", "stBBox", "stBbox":
+		ex.Op = OpStBBox
+	default:
+		return false, nil
+	}
+
+	if err := checkSpatial(ast.co, ex.Left.Col); err != nil {
+		return false, err
+	}
+
+	var err error
+	switch ex.Op {
+	case OpStBBox:
+		ex.Geo, err = parseGeoBBox(node)
+	default:
+		ex.Geo, err = parseGeo(node, (ex.Op == OpStDWithin))
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("[Where] %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// parseGeo parses a geometry value which can either be a variable or
+// string holding GeoJSON or an object with a point or geometry key
+func parseGeo(node *graph.Node, needsDistance bool) (*Geo, error) {
+	geo := &Geo{}
+
+	switch node.Type {
+	case graph.NodeVar:
+		geo.ValType = ValVar
+		geo.Val = node.Val
+
+	case graph.NodeStr:
+		geo.ValType = ValStr
+		if err := setGeoJSON(geo, node.Val); err != nil {
+			return nil, err
+		}
+
+	case graph.NodeObj:
+		for _, cn := range node.Children {
+			if err := setGeoKey(geo, cn.Name, cn); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("expecting a variable, string or object")
+	}
+
+	if geo.Val == "" && len(geo.Point) == 0 {
+		return nil, fmt.Errorf("'point' or 'geometry' required")
+	}
+	if needsDistance && geo.Distance == "" {
+		return nil, fmt.Errorf("'distance' required")
+	}
+	return geo, nil
+}
+
+func setGeoKey(geo *Geo, key string, node *graph.Node) error {
+	switch key {
+	case "point":
+		if node.Type != graph.NodeList || len(node.Children) != 2 {
+			return fmt.Errorf("'point' must be a list of [longitude, latitude]")
+		}
+		for _, v := range node.Children {
+			if v.Type != graph.NodeNum {
+				return fmt.Errorf("'point' must be a list of numbers")
+			}
+			geo.Point = append(geo.Point, v.Val)
+		}
+
+	case "geometry":
+		switch node.Type {
+		case graph.NodeVar:
+			geo.ValType = ValVar
+		case graph.NodeStr:
+			geo.ValType = ValStr
+			return setGeoJSON(geo, node.Val)
+		default:
+			return fmt.Errorf("'geometry' must be a variable or a string")
+		}
+		geo.Val = node.Val
+
+	case "distance":
+		switch node.Type {
+		case graph.NodeVar:
+			geo.DistType = ValVar
+		case graph.NodeNum:
+			geo.DistType = ValNum
+		default:
+			return fmt.Errorf("'distance' must be a variable or a number")
+		}
+		geo.Distance = node.Val
+
+	case "order":
+		// used by order by and handled there
+
+	default:
+		return fmt.Errorf("unknown key '%s'", key)
+	}
+	return nil
+}
+
+// setGeoJSON sets the GeoJSON from a string value, the string still
+// has its graphql escapes which are the same as the json ones
+func setGeoJSON(geo *Geo, v string) error {
+	var val string
+	if err := json.Unmarshal([]byte(`"`+v+`"`), &val); err != nil {
+		return fmt.Errorf("invalid geometry string: %w", err)
+	}
+	if !json.Valid([]byte(val)) {
+		return fmt.Errorf("geometry must be valid GeoJSON")
+	}
+	geo.Val = val
+	return nil
+}
+
+// parseGeoBBox parses a bounding box [min_lng, min_lat, max_lng, max_lat]
+func parseGeoBBox(node *graph.Node) (*Geo, error) {
+	geo := &Geo{}
+
+	switch node.Type {
+	case graph.NodeVar:
+		geo.ValType = ValVar
+		geo.Val = node.Val
+
+	case graph.NodeList:
+		if len(node.Children) != 4 {
+			return nil, fmt.Errorf("expecting a list of [min_lng, min_lat, max_lng, max_lat]")
+		}
+		for _, v := range node.Children {
+			if v.Type != graph.NodeNum {
+				return nil, fmt.Errorf("expecting a list of numbers")
+			}
+			geo.BBox = append(geo.BBox, v.Val)
+		}
+
+	default:
+		return nil, fmt.Errorf("expecting a variable or a list of numbers")
+	}
+	return geo, nil
+}
+
+func checkSpatial(co *Compiler, col sdata.DBColumn) error {
+	if co.s.DBType() == "mysql" {
+		return fmt.Errorf("spatial operators are not supported on mysql")
+	}
+	if !col.Spatial {
+		return fmt.Errorf("column '%s' is not a geometry or geography column", col.Name)
+	}
+	return nil
+}
+
+func isDistanceFunction(sel *Select, name string) bool {
+	if !strings.HasPrefix(name, distancePrefix) {
+		return false
+	}
+	col, ok := sel.Ti.ColumnExists(name[len(distancePrefix):])
+	return ok && (col.Spatial || col.Vector)
+}
+
+func (co *Compiler) compileDistanceFunction(sel *Select, name string) (
+	fn Function, err error,
+) {
+	var col sdata.DBColumn
+	if col, err = sel.Ti.GetColumn(name[len(distancePrefix):]); err != nil {
+		return
+	}
+	if col.Vector {
+		err = checkVector(co, col)
+	} else {
+		err = checkSpatial(co, col)
+	}
+	if err != nil {
+		return
+	}
+	fn.Name = "distance"
+	fn.Func = sdata.DBFunction{Name: fn.Name, Type: "double precision"}
+	fn.Args = []Arg{{Type: ArgTypeCol, Col: col}}
+
+	if col.Vector {
+		fn.Vector = &Vector{}
+	} else {
+		fn.Geo = &Geo{}
+	}
+	return
+}
+
+func (co *Compiler) compileDistanceArg(f *Field, arg graph.Arg) error {
+	if f.Geo == nil {
+		return unknownArg(arg)
+	}
+	return setGeoKey(f.Geo, arg.Name, arg.Val)
+}
+
+func validateDistance(f Field) error {
+	if f.Vector != nil && f.Vector.Val == "" {
+		return fmt.Errorf("distance '%s': argument 'vector' required",
+			f.FieldName)
+	}
+	if f.Geo == nil {
+		return nil
+	}
+	if f.Geo.Val == "" && len(f.Geo.Point) == 0 {
+		return fmt.Errorf("distance '%s': argument 'point' or 'geometry' required",
+			f.FieldName)
+	}
+	return nil
+}
+
+// compileOrderByDistance compiles a distance order
+// order_by: { distance_location: { point: [lng, lat], order: asc } }
+func (co *Compiler) compileOrderByDistance(sel *Select, node *graph.Node) (
+	ob OrderBy, ok bool, err error,
+) {
+	if !isDistanceFunction(sel, node.Name) {
+		return
+	}
+	ok = true
+
+	if ob.Col, err = sel.Ti.GetColumn(node.Name[len(distancePrefix):]); err != nil {
+		return
+	}
+	if ob.Col.Vector {
+		err = fmt.Errorf("use the 'nearest' argument to order by vector distance")
+		return
+	}
+	if err = checkSpatial(co, ob.Col); err != nil {
+		return
+	}
+	if ob.Geo, err = parseGeo(node, false); err != nil {
+		return
+	}
+
+	ob.Order = OrderAsc
+	for _, cn := range node.Children {
+		if cn.Name == "order" {
+			if ob.Order, err = toOrder(cn.Val); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
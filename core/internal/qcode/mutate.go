@@ -240,6 +240,23 @@ func parseDataValue(qc *QCode, actionVal *graph.Node, isJSON bool) (mData, error
 	return md, nil
 }
 
+// QType returns the operation type used to check the role
+// config of a mutation, connecting and disconnecting rows
+// updates them
+func (mt MType) QType() QType {
+	switch mt {
+	case MTInsert:
+		return QTInsert
+	case MTUpdate, MTConnect, MTDisconnect:
+		return QTUpdate
+	case MTUpsert:
+		return QTUpsert
+	case MTDelete:
+		return QTDelete
+	}
+	return QTUnknown
+}
+
 func parseMutationData(qc *QCode) (mData, error) {
 	var md mData
 	var err error
@@ -269,6 +286,10 @@ func (co *Compiler) newMutate(ms *mState, m Mutate, role string) error {
 	trv := co.getRole(role, m.Ti.Schema, m.Ti.Name, m.Key)
 	data := m.Data
 
+	if qt := m.Type.QType(); qt != QTUnknown {
+		ms.qc.addScopes(trv.scopes(qt), m.Key, qt)
+	}
+
 	items, err := co.processNestedMutations(ms, &m, data, trv)
 	if err != nil {
 		return err
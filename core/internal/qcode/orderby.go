@@ -55,6 +55,7 @@ func (co *Compiler) compileArgOrderByObj(sel *Select, parent *graph.Node, cm map
 			if ob.Order, err = toOrder(node.Val); err != nil { // sets the asc desc etc
 				continue
 			}
+			ob.SearchRank = (node.Name == "search_rank")
 
 		case graph.NodeList:
 			if ob, err = orderByFromList(node); err != nil {
@@ -62,6 +63,11 @@ func (co *Compiler) compileArgOrderByObj(sel *Select, parent *graph.Node, cm map
 			}
 
 		case graph.NodeObj:
+			var isDist bool
+			if ob, isDist, err = co.compileOrderByDistance(sel, node); isDist || err != nil {
+				break
+			}
+
 			var path []sdata.TPath
 			if path, err = co.FindPath(node.Name, sel.Ti.Name, ""); err != nil {
 				continue
@@ -84,15 +90,23 @@ func (co *Compiler) compileArgOrderByObj(sel *Select, parent *graph.Node, cm map
 			}
 		}
 
-		if err = co.setOrderByColName(ti, &ob, cn); err != nil {
+		if err != nil {
 			continue
 		}
 
-		if _, ok := cm[ob.Col.Name]; ok {
+		key := node.Name
+		if ob.Geo == nil && ob.Vector == nil && !ob.SearchRank {
+			if err = co.setOrderByColName(ti, &ob, cn); err != nil {
+				continue
+			}
+			key = ob.Col.Name
+		}
+
+		if _, ok := cm[key]; ok {
 			err = fmt.Errorf("can only be defined once")
 			continue
 		}
-		cm[ob.Col.Name] = struct{}{}
+		cm[key] = struct{}{}
 		obList = append(obList, ob)
 	}
 
@@ -75,6 +75,9 @@ type QCode struct {
 	Typename  bool
 	Query     []byte
 	Fragments []Fragment
+	Defers    []Defer
+	Defer     *Defer
+	Scopes    []Scope
 	actionArg graph.Arg
 }
 
@@ -102,6 +105,7 @@ type Select struct {
 	Ti         sdata.DBTable
 	Rel        sdata.DBRel
 	Joins      []Join
+	Search     *Search
 	order      Order
 	through    string
 	tc         TConfig
@@ -138,7 +142,11 @@ type Field struct {
 	FieldName   string
 	FieldFilter Filter
 	Args        []Arg
+	Window      *Window
+	Geo         *Geo
+	Vector      *Vector
 	SkipRender  SkipType
+	Defer       int32
 }
 
 type Column struct {
@@ -150,9 +158,20 @@ type Column struct {
 type Function struct {
 	Name string
 	// Col       sdata.DBColumn
-	Func sdata.DBFunction
-	Args []Arg
-	Agg  bool
+	Func   sdata.DBFunction
+	Args   []Arg
+	Agg    bool
+	Window *Window
+	Geo    *Geo
+	Vector *Vector
+}
+
+// Window holds the OVER clause of a window function field
+type Window struct {
+	Partition []sdata.DBColumn
+	OrderBy   []OrderBy
+	Offset    int32
+	Running   bool
 }
 
 type Filter struct {
@@ -182,10 +201,38 @@ type Exp struct {
 		ListVal  []string
 		Path     []string
 	}
+	Geo       *Geo
+	Search    *Search
 	Children  []*Exp
 	childrenA [5]*Exp
 }
 
+// Geo holds the geometry operand of a spatial (PostGIS) operator,
+// function or order by
+type Geo struct {
+	ValType  ValType
+	Val      string
+	Point    []string
+	BBox     []string
+	DistType ValType
+	Distance string
+}
+
+// Vector holds the query vector and distance metric used to
+// compare against a pgvector column
+type Vector struct {
+	Val    string
+	Metric VectorMetric
+}
+
+type VectorMetric int8
+
+const (
+	VectorL2 VectorMetric = iota
+	VectorCosine
+	VectorInnerProduct
+)
+
 type Join struct {
 	Filter *Exp
 	Rel    sdata.DBRel
@@ -209,11 +256,14 @@ type Arg struct {
 }
 
 type OrderBy struct {
-	KeyVar string
-	Key    string
-	Col    sdata.DBColumn
-	Var    string
-	Order  Order
+	KeyVar     string
+	Key        string
+	Col        sdata.DBColumn
+	Var        string
+	Geo        *Geo
+	Vector     *Vector
+	SearchRank bool
+	Order      Order
 }
 
 type PagingType int8
@@ -232,12 +282,22 @@ type Paging struct {
 	Offset    int32
 	Cursor    bool
 	NoLimit   bool
+	// the limit is the default limit and not set by the query or the role
+	DefaultLimit bool
 }
 
 type Cache struct {
 	Header string
 }
 
+// Scope is a scope the user needs to have to run an
+// operation on a table
+type Scope struct {
+	Name  string
+	Table string
+	Type  QType
+}
+
 type Var struct {
 	Name string
 	Val  json.RawMessage
@@ -283,6 +343,10 @@ const (
 	OpEqualsTrue
 	OpNotEqualsTrue
 	OpSelectExists
+	OpStDWithin
+	OpStIntersects
+	OpStContains
+	OpStBBox
 )
 
 type ValType int8
@@ -369,7 +433,7 @@ func (co *Compiler) Compile(
 	var buf bytes.Buffer
 	for i, v := range op.VarDef {
 		graphNodeToJSON(v.Val, &buf)
-		qc.Vars[i] = Var{Name: v.Name, Val: buf.Bytes()}
+		qc.Vars[i] = Var{Name: v.Name, Val: append(json.RawMessage(nil), buf.Bytes()...)}
 		buf.Reset()
 	}
 
@@ -385,6 +449,15 @@ func (co *Compiler) Compile(
 			return
 		}
 	}
+
+	if len(qc.Defers) != 0 && qc.Type != QTQuery {
+		err = fmt.Errorf("@defer and @stream are only supported in queries")
+		return
+	}
+
+	if err = co.orderDeferred(qc); err != nil {
+		return
+	}
 	return
 }
 
@@ -512,6 +585,10 @@ func (co *Compiler) compileQuery(qc *QCode, op *graph.Operation, role string) er
 			return err
 		}
 
+		if err := validateDefer(qc, sel); err != nil {
+			return err
+		}
+
 		qc.Selects = append(qc.Selects, s1)
 		id++
 	}
@@ -838,9 +915,51 @@ func (co *Compiler) setSelectorRoleConfig(role, fieldName string, qc *QCode, sel
 		}
 		sel.SkipRender = SkipTypeBlocked
 	}
+
+	// the rows returned by a mutation are read so every selector needs the
+	// query scopes, the mutation scopes are only needed for the target table
+	qc.addScopes(tr.scopes(QTQuery), fieldName, QTQuery)
+	if qc.SType != QTQuery && sel.ParentID == -1 {
+		qc.addScopes(tr.scopes(qc.SType), fieldName, qc.SType)
+	}
 	return tr, nil
 }
 
+// addScopes adds the scopes required by an operation on a table
+func (qc *QCode) addScopes(scopes []string, table string, qt QType) {
+	for _, name := range scopes {
+		s := Scope{Name: name, Table: table, Type: qt}
+		found := false
+		for _, v := range qc.Scopes {
+			if v == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			qc.Scopes = append(qc.Scopes, s)
+		}
+	}
+}
+
+// CheckScopes returns an error if the user does not have
+// all the scopes required by the operation
+func (qc *QCode) CheckScopes(userScopes []string) error {
+	for _, s := range qc.Scopes {
+		found := false
+		for _, v := range userScopes {
+			if v == s.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s blocked: %s (missing scope: %s)", s.Type, s.Table, s.Name)
+		}
+	}
+	return nil
+}
+
 func (co *Compiler) setLimit(tr trval, qc *QCode, sel *Select) {
 	if sel.Paging.Limit != 0 {
 		return
@@ -852,10 +971,12 @@ func (co *Compiler) setLimit(tr trval, qc *QCode, sel *Select) {
 		// Else use default limit from config
 	} else if co.c.DefaultLimit != 0 {
 		sel.Paging.Limit = int32(co.c.DefaultLimit)
+		sel.Paging.DefaultLimit = true
 
 		// Else just go with 20
 	} else {
 		sel.Paging.Limit = 20
+		sel.Paging.DefaultLimit = true
 	}
 }
 
@@ -954,6 +1075,23 @@ func (co *Compiler) addSeekPredicate(sel *Select) {
 }
 
 func (co *Compiler) validateSelect(sel *Select) error {
+	if sel.Paging.Cursor {
+		for _, ob := range sel.OrderBy {
+			if ob.Geo != nil || ob.Vector != nil {
+				return fmt.Errorf("distance ordering cannot be used with cursor pagination")
+			}
+			if ob.SearchRank {
+				return fmt.Errorf("search rank ordering cannot be used with cursor pagination")
+			}
+		}
+	}
+
+	for _, ob := range sel.OrderBy {
+		if ob.SearchRank && sel.Search == nil {
+			return fmt.Errorf("search argument not found: search_rank")
+		}
+	}
+
 	if sel.Rel.Type == sdata.RelRecursive {
 		v, ok := sel.GetInternalArg("find")
 		if !ok {
@@ -981,6 +1119,76 @@ func addFilters(qc *QCode, where *Filter, trv trval) bool {
 	return false
 }
 
+// AddFilters returns a copy of the compiled query with the filters added to
+// the where clause of the tables, the compiled query itself is not modified
+// since it's shared by all the requests of a role
+func (co *Compiler) AddFilters(qc *QCode, filters map[string][]string) (*QCode, error) {
+	qc1 := *qc
+	qc1.Selects = append([]Select(nil), qc.Selects...)
+	qc1.Mutates = append([]Mutate(nil), qc.Mutates...)
+
+	for i := range qc1.Selects {
+		sel := &qc1.Selects[i]
+		fl, ok := filters[sel.Ti.Name]
+		if !ok || len(fl) == 0 {
+			continue
+		}
+		ex, _, err := compileFilter(co.s, sel.Ti, fl, false)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sel.Ti.Name, err)
+		}
+		addAndFilter(&sel.Where, ex)
+	}
+
+	// only mutations that select the rows they change have a where clause
+	for i := range qc1.Mutates {
+		m := &qc1.Mutates[i]
+		fl, ok := filters[m.Ti.Name]
+		if !ok || len(fl) == 0 || m.Where.Exp == nil {
+			continue
+		}
+		ex, _, err := compileFilter(co.s, m.Ti, fl, m.IsJSON)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Ti.Name, err)
+		}
+		addAndFilter(&m.Where, ex)
+	}
+	return &qc1, nil
+}
+
+// FilterVars returns the names of the variables used by the filters
+func FilterVars(filters map[string][]string) ([]string, error) {
+	var vars []string
+	seen := make(map[string]struct{})
+
+	var walk func(n *graph.Node)
+	walk = func(n *graph.Node) {
+		if n.Type == graph.NodeVar {
+			if _, ok := seen[n.Val]; !ok {
+				seen[n.Val] = struct{}{}
+				vars = append(vars, n.Val)
+			}
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+
+	for _, fl := range filters {
+		for _, v := range fl {
+			if v == "false" {
+				continue
+			}
+			node, err := graph.ParseArgValue(v, false)
+			if err != nil {
+				return nil, err
+			}
+			walk(node)
+		}
+	}
+	return vars, nil
+}
+
 func (co *Compiler) setMutationType(qc *QCode, op *graph.Operation, role string) error {
 	var err error
 
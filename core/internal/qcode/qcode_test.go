@@ -3,6 +3,7 @@ package qcode_test
 import (
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/dosco/graphjin/core/v3/internal/qcode"
@@ -123,6 +124,147 @@ func TestCompile4(t *testing.T) {
 	}
 }
 
+func TestCompileScopes(t *testing.T) {
+	qc, _ := qcode.NewCompiler(dbs, qcode.Config{})
+	err := qc.AddRole("user", "public", "products", qcode.TRConfig{
+		Query:  qcode.QueryConfig{Scopes: []string{"read:products"}},
+		Insert: qcode.InsertConfig{Scopes: []string{"write:products"}},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := qc.Compile([]byte(`
+	query { products(id: 15) {
+			id
+			name
+		} }`), nil, "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := res.CheckScopes(nil); err == nil {
+		t.Fatal(errors.New("expected an error: 'read:products' scope missing"))
+	}
+
+	if err := res.CheckScopes([]string{"read:products"}); err != nil {
+		t.Fatal(err)
+	}
+
+	vars := map[string]json.RawMessage{
+		"data": json.RawMessage(`{ "name": "my_name", "description": "my_desc" }`),
+	}
+
+	res, err = qc.Compile([]byte(`
+	mutation {
+		products(insert: $data) {
+			id
+		}
+	}`), vars, "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = res.CheckScopes([]string{"read:products"})
+	if err == nil || err.Error() != "Insert blocked: products (missing scope: write:products)" {
+		t.Fatalf("expected an error: 'write:products' scope missing, got: %v", err)
+	}
+
+	// the rows returned by the mutation need the query scopes
+	err = res.CheckScopes([]string{"write:products"})
+	if err == nil || err.Error() != "Query blocked: products (missing scope: read:products)" {
+		t.Fatalf("expected an error: 'read:products' scope missing, got: %v", err)
+	}
+
+	if err := res.CheckScopes([]string{"read:products", "write:products"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = qc.AddRole("user", "public", "users", qcode.TRConfig{
+		Query:  qcode.QueryConfig{Scopes: []string{"read:users"}},
+		Insert: qcode.InsertConfig{Scopes: []string{"write:users"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = qc.Compile([]byte(`
+	mutation {
+		products(insert: $data) {
+			id
+			user {
+				email
+			}
+		}
+	}`), vars, "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a table joined to the returned rows needs its query scopes
+	// and not the mutation scopes
+	err = res.CheckScopes([]string{"read:products", "write:products"})
+	if err == nil || err.Error() != "Query blocked: user (missing scope: read:users)" {
+		t.Fatalf("expected an error: 'read:users' scope missing, got: %v", err)
+	}
+
+	if err := res.CheckScopes([]string{"read:products", "write:products", "read:users"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAddFilters(t *testing.T) {
+	qc, _ := qcode.NewCompiler(dbs, qcode.Config{})
+	err := qc.AddRole("user", "public", "products", qcode.TRConfig{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := qc.Compile([]byte(`
+	query { products {
+			id
+			name
+		} }`), nil, "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res1, err := qc.AddFilters(res, map[string][]string{
+		"products": {"{ price: { gt: 10 } }"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Selects[0].Where.Exp != nil {
+		t.Fatal(errors.New("the compiled query should not be modified"))
+	}
+
+	if res1.Selects[0].Where.Exp == nil {
+		t.Fatal(errors.New("expected the filter to be added"))
+	}
+
+	_, err = qc.AddFilters(res, map[string][]string{
+		"products": {"{ not_a_column: { gt: 10 } }"},
+	})
+	if err == nil {
+		t.Fatal(errors.New("expected an error: invalid filter column"))
+	}
+
+	vars, err := qcode.FilterVars(map[string][]string{
+		"products": {"{ and: [{ org_id: { eq: $org_id } }, { id: { in: [$id, 5] } }] }"},
+		"users":    {"false"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(vars, ",") != "org_id,id" {
+		t.Fatalf("expected the variables org_id and id, got: %v", vars)
+	}
+}
+
 func TestInvalidCompile1(t *testing.T) {
 	qcompile, _ := qcode.NewCompiler(dbs, qcode.Config{})
 	_, err := qcompile.Compile([]byte(`#`), nil, "user", "")
@@ -347,3 +489,78 @@ func BenchmarkQCompileFragment(b *testing.B) {
 		}
 	}
 }
+
+func TestCompileWindowFunctionPrefix(t *testing.T) {
+	di := sdata.GetTestDBInfo()
+	di.Functions = append(di.Functions, sdata.DBFunction{
+		Schema: "public",
+		Name:   "lead_score",
+		Type:   "numeric",
+		Inputs: []sdata.DBFuncParam{{ID: 1, Name: "id", Type: "bigint"}},
+	})
+
+	schema, err := sdata.NewDBSchema(di, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qc, _ := qcode.NewCompiler(schema, qcode.Config{})
+
+	// lead_score is a database function and not the lead window
+	// function applied to a column named score
+	res, err := qc.Compile([]byte(`
+	query { products {
+			id
+			lead_score(args: { id: id })
+			lead_price(order_by: { id: asc })
+		} }`), nil, "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := res.Selects[0].Fields
+	if len(fields) != 3 {
+		t.Fatalf("expected 3 fields got %d", len(fields))
+	}
+	if fields[1].Func.Name != "lead_score" || fields[1].Window != nil {
+		t.Errorf("expected the database function lead_score got: %+v", fields[1].Func)
+	}
+	if fields[2].Func.Name != "lead" || fields[2].Window == nil {
+		t.Errorf("expected the window function lead got: %+v", fields[2].Func)
+	}
+}
+
+func TestCompileSpatialOrderByBlocked(t *testing.T) {
+	qc, _ := qcode.NewCompiler(dbs, qcode.Config{})
+	err := qc.AddRole("user", "public", "stores", qcode.TRConfig{
+		Query: qcode.QueryConfig{
+			Columns: []string{"id", "name"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = qc.Compile([]byte(`
+	query { stores(order_by: { distance_location: { point: [-122.4, 37.7] } }) {
+			id
+		} }`), nil, "user", "")
+
+	if err == nil {
+		t.Fatal("expected the blocked column to fail in order_by")
+	}
+}
+
+func TestCompileNearestCamelcase(t *testing.T) {
+	qc, _ := qcode.NewCompiler(dbs, qcode.Config{EnableCamelcase: true})
+
+	_, err := qc.Compile([]byte(`
+	query {
+		documents(nearest: { column: Embedding, vector: $v }) {
+			id
+		}
+	}`), nil, "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
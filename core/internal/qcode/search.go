@@ -0,0 +1,150 @@
+package qcode
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/dosco/graphjin/core/v3/internal/graph"
+)
+
+// Search holds the full-text search options set using the
+// search argument
+type Search struct {
+	ValType    ValType
+	Val        string
+	Mode       SearchMode
+	ConfigType ValType
+	Config     string
+	// rank weights in the postgres {D, C, B, A} order
+	Weights []string
+}
+
+type SearchMode int8
+
+const (
+	SearchDefault SearchMode = iota
+	SearchWebsearch
+	SearchPhrase
+	SearchPlain
+	SearchRaw
+)
+
+var searchConfigRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// compileSearch parses the value of the search argument which can
+// either be the query or an object with the search options
+// search: { query: $q, mode: phrase, config: "english", weights: { a: 1.0 } }
+func (co *Compiler) compileSearch(node *graph.Node) (*Search, error) {
+	s := &Search{}
+
+	switch node.Type {
+	case graph.NodeStr, graph.NodeVar:
+		setSearchVal(s, node)
+		return s, nil
+
+	case graph.NodeObj:
+		for _, cn := range node.Children {
+			if err := co.setSearchKey(s, cn); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("expecting a string, variable or object")
+	}
+
+	if s.Val == "" {
+		return nil, fmt.Errorf("'query' required")
+	}
+	return s, nil
+}
+
+func (co *Compiler) setSearchKey(s *Search, node *graph.Node) (err error) {
+	isMysql := co.s.DBType() == "mysql"
+
+	switch node.Name {
+	case "query":
+		if node.Type != graph.NodeStr && node.Type != graph.NodeVar {
+			return fmt.Errorf("'query' must be a string or a variable")
+		}
+		setSearchVal(s, node)
+
+	case "mode":
+		s.Mode, err = toSearchMode(node.Val)
+
+	case "config":
+		if isMysql {
+			return fmt.Errorf("'config' is not supported on mysql")
+		}
+		switch {
+		case node.Type == graph.NodeVar:
+			s.ConfigType = ValVar
+		case searchConfigRe.MatchString(node.Val):
+			s.ConfigType = ValStr
+		default:
+			return fmt.Errorf("invalid search config '%s'", node.Val)
+		}
+		s.Config = node.Val
+
+	case "weights":
+		if isMysql {
+			return fmt.Errorf("'weights' are not supported on mysql")
+		}
+		s.Weights, err = parseSearchWeights(node)
+
+	default:
+		err = fmt.Errorf("unknown key '%s'", node.Name)
+	}
+	return
+}
+
+func setSearchVal(s *Search, node *graph.Node) {
+	if node.Type == graph.NodeVar {
+		s.ValType = ValVar
+	} else {
+		s.ValType = ValStr
+	}
+	s.Val = node.Val
+}
+
+func toSearchMode(val string) (SearchMode, error) {
+	switch val {
+	case "websearch":
+		return SearchWebsearch, nil
+	case "phrase":
+		return SearchPhrase, nil
+	case "plain":
+		return SearchPlain, nil
+	case "raw":
+		return SearchRaw, nil
+	}
+	return 0, fmt.Errorf("valid values for 'mode' are 'websearch', 'phrase', 'plain' and 'raw'")
+}
+
+// parseSearchWeights parses { a: 1.0, b: 0.4, c: 0.2, d: 0.1 } with
+// missing weights set to the postgres defaults
+func parseSearchWeights(node *graph.Node) ([]string, error) {
+	if node.Type != graph.NodeObj {
+		return nil, fmt.Errorf("'weights' must be an object eg. { a: 1.0, b: 0.4 }")
+	}
+	w := []string{"0.1", "0.2", "0.4", "1.0"}
+
+	for _, cn := range node.Children {
+		if cn.Type != graph.NodeNum {
+			return nil, fmt.Errorf("weight '%s' must be a number", cn.Name)
+		}
+		switch cn.Name {
+		case "a", "A":
+			w[3] = cn.Val
+		case "b", "B":
+			w[2] = cn.Val
+		case "c", "C":
+			w[1] = cn.Val
+		case "d", "D":
+			w[0] = cn.Val
+		default:
+			return nil, fmt.Errorf("valid weights are 'a', 'b', 'c' and 'd'")
+		}
+	}
+	return w, nil
+}
@@ -0,0 +1,100 @@
+package qcode
+
+import (
+	"fmt"
+
+	"github.com/dosco/graphjin/core/v3/internal/graph"
+	"github.com/dosco/graphjin/core/v3/internal/sdata"
+)
+
+// compileArgNearest orders the rows by their distance to a vector
+// nearest: { column: embedding, vector: $v, metric: cosine }
+func (co *Compiler) compileArgNearest(sel *Select, arg graph.Arg) (err error) {
+	if err = validateArg(arg, graph.NodeObj); err != nil {
+		return
+	}
+
+	ob := OrderBy{Order: OrderAsc, Vector: &Vector{}}
+
+	for _, cn := range arg.Val.Children {
+		switch cn.Name {
+		case "column":
+			if cn.Type != graph.NodeLabel && cn.Type != graph.NodeStr {
+				return fmt.Errorf("'column' must be a column name")
+			}
+			if ob.Col, err = sel.Ti.GetColumn(co.ParseName(cn.Val)); err != nil {
+				return
+			}
+			if err = checkVector(co, ob.Col); err != nil {
+				return
+			}
+
+		case "vector":
+			if err = setVectorVal(ob.Vector, cn); err != nil {
+				return
+			}
+
+		case "metric":
+			if ob.Vector.Metric, err = toVectorMetric(cn); err != nil {
+				return
+			}
+
+		default:
+			return fmt.Errorf("unknown key '%s'", cn.Name)
+		}
+	}
+
+	if ob.Col.Name == "" {
+		return fmt.Errorf("'column' required")
+	}
+	if ob.Vector.Val == "" {
+		return fmt.Errorf("'vector' required")
+	}
+
+	// the nearest rows always come first
+	sel.OrderBy = append([]OrderBy{ob}, sel.OrderBy...)
+	return
+}
+
+func (co *Compiler) compileVectorArg(f *Field, arg graph.Arg) (err error) {
+	if f.Vector == nil {
+		return unknownArg(arg)
+	}
+	switch arg.Name {
+	case "vector":
+		err = setVectorVal(f.Vector, arg.Val)
+	case "metric":
+		f.Vector.Metric, err = toVectorMetric(arg.Val)
+	}
+	return
+}
+
+func setVectorVal(v *Vector, node *graph.Node) error {
+	if node.Type != graph.NodeVar {
+		return fmt.Errorf("'vector' must be a variable")
+	}
+	v.Val = node.Val
+	return nil
+}
+
+func toVectorMetric(node *graph.Node) (VectorMetric, error) {
+	switch node.Val {
+	case "l2", "euclidean":
+		return VectorL2, nil
+	case "cosine":
+		return VectorCosine, nil
+	case "inner_product", "innerProduct", "ip":
+		return VectorInnerProduct, nil
+	}
+	return 0, fmt.Errorf("valid values for 'metric' are 'l2', 'cosine' and 'inner_product'")
+}
+
+func checkVector(co *Compiler, col sdata.DBColumn) error {
+	if co.s.DBType() == "mysql" {
+		return fmt.Errorf("vector search is not supported on mysql")
+	}
+	if !col.Vector {
+		return fmt.Errorf("column '%s' is not a vector column", col.Name)
+	}
+	return nil
+}
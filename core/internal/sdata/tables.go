@@ -194,6 +194,8 @@ func NewDBTable(schema, name, _type string, cols []DBColumn) DBTable {
 	for i, c := range cols {
 		cols[i].Schema = schema
 		cols[i].Table = name
+		cols[i].Spatial = isSpatialType(c.Type)
+		cols[i].Vector = isVectorType(c.Type)
 
 		switch {
 		case c.FullText:
@@ -208,6 +210,22 @@ func NewDBTable(schema, name, _type string, cols []DBColumn) DBTable {
 	return ti
 }
 
+// isSpatialType returns true for PostGIS geometry and geography types
+// eg. geometry(Point,4326)
+func isSpatialType(t string) bool {
+	return strings.HasPrefix(t, "geometry") || strings.HasPrefix(t, "geography")
+}
+
+// isVectorType returns true for pgvector types eg. vector(1536)
+func isVectorType(t string) bool {
+	return t == "vector" || strings.HasPrefix(t, "vector(")
+}
+
+// IsGeography returns true if the column is a PostGIS geography column
+func (col DBColumn) IsGeography() bool {
+	return strings.HasPrefix(col.Type, "geography")
+}
+
 // AddTable adds a table to the DBInfo object
 func (di *DBInfo) AddTable(t DBTable) {
 	for i, c := range t.Columns {
@@ -255,6 +273,8 @@ type DBColumn struct {
 	PrimaryKey  bool
 	UniqueKey   bool
 	FullText    bool
+	Spatial     bool
+	Vector      bool
 	FKRecursive bool
 	FKeySchema  string
 	FKeyTable   string
@@ -23,3 +23,30 @@ func TestIsInList(t *testing.T) {
 		}
 	}
 }
+
+func TestIsSpatialType(t *testing.T) {
+	for value, isSpatial := range map[string]bool{
+		"geometry":                    true,
+		"geometry(Point,4326)":        true,
+		"geography(Polygon,4326)":     true,
+		"text":                        false,
+		"timestamp without time zone": false,
+	} {
+		if isSpatialType(value) != isSpatial {
+			t.Fatalf("expected isSpatialType(%s) to be %t", value, isSpatial)
+		}
+	}
+}
+
+func TestIsVectorType(t *testing.T) {
+	for value, isVector := range map[string]bool{
+		"vector":            true,
+		"vector(1536)":      true,
+		"text":              false,
+		"character varying": false,
+	} {
+		if isVectorType(value) != isVector {
+			t.Fatalf("expected isVectorType(%s) to be %t", value, isVector)
+		}
+	}
+}
@@ -30,6 +30,16 @@ func GetTestDBInfo() *DBInfo {
 			{Schema: "public", Table: "products", Name: "tsv", Type: "tsvector", NotNull: false, PrimaryKey: false, UniqueKey: false, FullText: true},
 			{Schema: "public", Table: "products", Name: "tags", Type: "text[]", NotNull: false, PrimaryKey: false, UniqueKey: false, FKeySchema: "public", FKeyTable: "tags", FKeyCol: "slug", Array: true},
 			{Schema: "public", Table: "products", Name: "tag_count", Type: "json", NotNull: false, PrimaryKey: false, UniqueKey: false, FKeySchema: "public", FKeyTable: "tag_count", FKeyCol: ""}},
+		{
+			{Schema: "public", Table: "stores", Name: "id", Type: "bigint", NotNull: true, PrimaryKey: true, UniqueKey: true},
+			{Schema: "public", Table: "stores", Name: "name", Type: "character varying", NotNull: false, PrimaryKey: false, UniqueKey: false},
+			{Schema: "public", Table: "stores", Name: "location", Type: "geography(Point,4326)", NotNull: false, PrimaryKey: false, UniqueKey: false},
+			{Schema: "public", Table: "stores", Name: "delivery_area", Type: "geometry(Polygon,4326)", NotNull: false, PrimaryKey: false, UniqueKey: false}},
+		{
+			{Schema: "public", Table: "documents", Name: "id", Type: "bigint", NotNull: true, PrimaryKey: true, UniqueKey: true},
+			{Schema: "public", Table: "documents", Name: "title", Type: "text", NotNull: false, PrimaryKey: false, UniqueKey: false},
+			{Schema: "public", Table: "documents", Name: "owner_id", Type: "bigint", NotNull: false, PrimaryKey: false, UniqueKey: false, FKeySchema: "public", FKeyTable: "users", FKeyCol: "id"},
+			{Schema: "public", Table: "documents", Name: "embedding", Type: "vector(3)", NotNull: false, PrimaryKey: false, UniqueKey: false}},
 		{
 			{Schema: "public", Table: "purchases", Name: "id", Type: "bigint", NotNull: true, PrimaryKey: true, UniqueKey: true},
 			{Schema: "public", Table: "purchases", Name: "customer_id", Type: "bigint", NotNull: false, PrimaryKey: false, UniqueKey: false, FKeySchema: "public", FKeyTable: "customers", FKeyCol: "id"},
@@ -23,6 +23,8 @@ const (
 	LOC_MUTATION     = "MUTATION"
 	LOC_SUBSCRIPTION = "SUBSCRIPTION"
 	LOC_FIELD        = "FIELD"
+	LOC_FRAG_SPREAD  = "FRAGMENT_SPREAD"
+	LOC_INLINE_FRAG  = "INLINE_FRAGMENT"
 
 	SUFFIX_EXP      = "Expression"
 	SUFFIX_LISTEXP  = "ListExpression"
@@ -144,6 +146,24 @@ var stdTypes = []FullType{
 		Name:       "Mutation",
 		Interfaces: []TypeRef{},
 		Fields:     []FieldObject{},
+	}, {
+		Kind:        KIND_INPUT_OBJ,
+		Name:        "NearestInput",
+		Description: "Order by distance to a vector",
+		Interfaces:  []TypeRef{},
+		InputFields: []InputValue{{
+			Name:        "column",
+			Description: "Vector column to compare against",
+			Type:        newTypeRef("", "String", nil),
+		}, {
+			Name:        "vector",
+			Description: "Query vector",
+			Type:        newTypeRef("LIST", "", newTypeRef("", "Float", nil)),
+		}, {
+			Name:        "metric",
+			Description: "Distance metric: l2, cosine or inner_product",
+			Type:        newTypeRef("", "String", nil),
+		}},
 	}, {
 		Kind: KIND_ENUM,
 		Name: "FindSearchInput",
@@ -244,6 +264,9 @@ func (gj *graphjinEngine) introQuery() (result json.RawMessage, err error) {
 	v = append(expAll, expJSON...)
 	in.addExpTypes(v, "JSON", newTypeRef("", "String", nil))
 
+	v = append(expAll, expGeo...)
+	in.addExpTypes(v, "Geometry", newTypeRef("", "JSON", nil))
+
 	// Add the roles
 	in.addRolesEnumType(gj.roles)
 	in.addTablesEnumType()
@@ -383,7 +406,7 @@ func (in *Introspection) addTableTypeWithDepth(
 	ft.Name = name
 	ft.Description = table.Comment
 
-	var hasSearch bool
+	var hasSearch, hasVector bool
 	var hasRecursive bool
 
 	if err = in.addColumnsEnumType(table); err != nil {
@@ -402,6 +425,9 @@ func (in *Introspection) addTableTypeWithDepth(
 		if c.FullText {
 			hasSearch = true
 		}
+		if c.Vector {
+			hasVector = true
+		}
 		if c.FKRecursive {
 			hasRecursive = true
 		}
@@ -457,6 +483,10 @@ func (in *Introspection) addTableTypeWithDepth(
 		ft.addArg("search", newTypeRef("", "String", nil))
 	}
 
+	if hasVector {
+		ft.addArg("nearest", newTypeRef("", "NearestInput", nil))
+	}
+
 	if depth > 1 {
 		return
 	}
@@ -574,7 +604,9 @@ func (in *Introspection) addWhereType(table sdata.DBTable, ft *FullType) {
 			continue
 		}
 		ft := getTypeFromColumn(c)
-		if c.Array {
+		if c.Spatial {
+			ft = "Geometry" + SUFFIX_EXP
+		} else if c.Array {
 			ft += SUFFIX_LISTEXP
 		} else {
 			ft += SUFFIX_EXP
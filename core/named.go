@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/dosco/graphjin/core/v3/internal/psql"
+	"github.com/dosco/graphjin/core/v3/internal/qcode"
+)
+
+// NamedQuery describes a query from the allow list and the variables it uses
+type NamedQuery struct {
+	Namespace string
+	Name      string
+	Operation OpType
+	Vars      []NamedQueryVar
+}
+
+// NamedQueryVar describes a variable used by a named query
+type NamedQueryVar struct {
+	Name string
+
+	// GraphQL type of the variable (Int, Float, Boolean, String or JSON)
+	Type string
+
+	Array    bool
+	Required bool
+
+	// Default value set in the query
+	Default json.RawMessage
+}
+
+// FullName returns the name the query is called by
+func (nq NamedQuery) FullName() string {
+	if nq.Namespace != "" {
+		return nq.Namespace + "." + nq.Name
+	}
+	return nq.Name
+}
+
+// NamedQueries returns the queries in the allow list along with the variables
+// they use. The queries are compiled for the role of the user in the context
+// and the ones the role cannot use are left out.
+func (g *GraphJin) NamedQueries(c context.Context) (nql []NamedQuery, err error) {
+	gj := g.Load().(*graphjinEngine)
+
+	items, err := gj.allowList.ListAll()
+	if err != nil {
+		return
+	}
+
+	for _, item := range items {
+		st, err1 := gj.compileItem(c, item)
+		if err1 != nil {
+			continue
+		}
+
+		nq := NamedQuery{
+			Namespace: item.Namespace,
+			Name:      item.Name,
+			Operation: OpQuery,
+			Vars:      queryVars(st.qc, st.md),
+		}
+		if st.qc.SType != qcode.QTQuery {
+			nq.Operation = OpMutation
+		}
+		nql = append(nql, nq)
+	}
+	return
+}
+
+// queryVars returns the variables used by the query, the types come from
+// the query parameters and the default values
+func queryVars(qc *qcode.QCode, md psql.Metadata) (vars []NamedQueryVar) {
+	vmap := make(map[string]int)
+
+	for _, p := range md.Params() {
+		// literal values from the query
+		if p.HasValue {
+			continue
+		}
+
+		// set from the request context
+		if isContextVar(p.Name) {
+			continue
+		}
+
+		if _, ok := vmap[p.Name]; ok {
+			continue
+		}
+
+		t, list := getType(p.Type)
+		vmap[p.Name] = len(vars)
+		vars = append(vars, NamedQueryVar{
+			Name:     p.Name,
+			Type:     t,
+			Array:    p.IsArray || list,
+			Required: p.IsNotNull,
+		})
+	}
+
+	for _, v := range qc.Vars {
+		if i, ok := vmap[v.Name]; ok {
+			vars[i].Default = v.Val
+			continue
+		}
+		vars = append(vars, NamedQueryVar{
+			Name:    v.Name,
+			Type:    jsonValueType(v.Val),
+			Default: v.Val,
+		})
+	}
+	return
+}
+
+// jsonValueType returns the GraphQL type of a json value
+func jsonValueType(v json.RawMessage) string {
+	if len(v) == 0 {
+		return "JSON"
+	}
+
+	switch v[0] {
+	case '"':
+		return "String"
+	case 't', 'f':
+		return "Boolean"
+	case '[', '{', 'n':
+		return "JSON"
+	}
+
+	if strings.ContainsAny(string(v), ".eE") {
+		return "Float"
+	}
+	return "Int"
+}
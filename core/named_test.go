@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNamedQueries(t *testing.T) {
+	g := newTestAllowListGraphJin(t)
+
+	c := context.WithValue(context.Background(), UserIDKey, 1)
+	nql, err := g.NamedQueries(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nql) != 2 {
+		t.Fatalf("expected 2 named queries got %d", len(nql))
+	}
+
+	nq := nql[1]
+	if nq.Name != "getProducts" || nq.Operation != OpQuery {
+		t.Fatalf("expected query getProducts got %s", nq.Name)
+	}
+
+	vars := make(map[string]NamedQueryVar)
+	for _, v := range nq.Vars {
+		vars[v.Name] = v
+	}
+	if v := vars["price"]; v.Type != "Float" {
+		t.Errorf("expected price of type Float got '%s'", v.Type)
+	}
+	if v := vars["limit"]; v.Type != "Int" || string(v.Default) != "10" {
+		t.Errorf("expected limit of type Int with a default of 10 got '%s' %s", v.Type, v.Default)
+	}
+
+	if nql[0].Name != "createProduct" || nql[0].Operation != OpMutation {
+		t.Fatalf("expected mutation createProduct got %s", nql[0].Name)
+	}
+}
@@ -0,0 +1,587 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/dosco/graphjin/core/v3/internal/allow"
+	"github.com/dosco/graphjin/core/v3/internal/psql"
+	"github.com/dosco/graphjin/core/v3/internal/qcode"
+	"github.com/dosco/graphjin/core/v3/internal/sdata"
+)
+
+// OpenAPIConfig is used to set the details of the generated OpenAPI document
+type OpenAPIConfig struct {
+	// Title of the API
+	Title string
+
+	// Version of the API
+	Version string
+
+	// Path the REST endpoints are served under (eg. /api/v1/rest)
+	BasePath string
+
+	// Routes mapped to the named queries (eg. GET /users/{id})
+	Routes []OpenAPIRoute
+}
+
+// OpenAPIRoute is a REST route mapped to a named query
+type OpenAPIRoute struct {
+	// HTTP method of the route
+	Method string
+
+	// Path of the route, path parameters are set using braces
+	Path string
+
+	// Name of the query from the allow list
+	Query string
+
+	// Query string parameters bound to variables (parameter: variable)
+	Params map[string]string
+
+	// HTTP headers bound to variables (header: variable)
+	Headers map[string]string
+
+	// Variable the request body is bound to
+	Body string
+}
+
+type openAPIDoc struct {
+	OpenAPI string                  `json:"openapi"`
+	Info    openAPIInfo             `json:"info"`
+	Paths   map[string]*openAPIPath `json:"paths"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIPath struct {
+	Get    *openAPIOperation `json:"get,omitempty"`
+	Post   *openAPIOperation `json:"post,omitempty"`
+	Put    *openAPIOperation `json:"put,omitempty"`
+	Patch  *openAPIOperation `json:"patch,omitempty"`
+	Delete *openAPIOperation `json:"delete,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []openAPIParam             `json:"parameters,omitempty"`
+	RequestBody *openAPIBody               `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParam struct {
+	Name     string                  `json:"name"`
+	In       string                  `json:"in"`
+	Required bool                    `json:"required,omitempty"`
+	Schema   *openAPISchema          `json:"schema,omitempty"`
+	Content  map[string]openAPIMedia `json:"content,omitempty"`
+}
+
+type openAPIBody struct {
+	Required bool                    `json:"required,omitempty"`
+	Content  map[string]openAPIMedia `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                  `json:"description"`
+	Content     map[string]openAPIMedia `json:"content,omitempty"`
+}
+
+type openAPIMedia struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Type        string                    `json:"type,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	Nullable    bool                      `json:"nullable,omitempty"`
+	Items       *openAPISchema            `json:"items,omitempty"`
+	Properties  map[string]*openAPISchema `json:"properties,omitempty"`
+	Required    []string                  `json:"required,omitempty"`
+	OneOf       []*openAPISchema          `json:"oneOf,omitempty"`
+	Default     json.RawMessage           `json:"default,omitempty"`
+}
+
+// OpenAPI returns an OpenAPI 3 document describing the REST endpoints of the
+// named queries in the allow list and the routes mapped to them. The queries
+// are compiled for the role of the user in the context and the ones the role
+// cannot use are left out.
+func (g *GraphJin) OpenAPI(c context.Context, conf OpenAPIConfig) (doc json.RawMessage, err error) {
+	gj := g.Load().(*graphjinEngine)
+
+	items, err := gj.allowList.ListAll()
+	if err != nil {
+		return
+	}
+
+	if conf.Title == "" {
+		conf.Title = "GraphJin"
+	}
+	if conf.Version == "" {
+		conf.Version = "1.0.0"
+	}
+
+	d := openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: conf.Title, Version: conf.Version},
+		Paths:   make(map[string]*openAPIPath, len(items)),
+	}
+
+	type namedStmt struct {
+		item allow.Item
+		st   stmt
+	}
+	named := make(map[string]namedStmt, len(items))
+
+	for _, item := range items {
+		st, err1 := gj.compileItem(c, item)
+		if err1 != nil {
+			gj.log.Printf("openapi: query skipped: %s: %s", item.Name, err1)
+			continue
+		}
+
+		name := item.Name
+		if item.Namespace != "" {
+			name = item.Namespace + "." + item.Name
+		}
+		d.Paths[path.Join("/", conf.BasePath, name)] = newOpenAPIPath(name, item, st)
+
+		if _, ok := named[item.Name]; !ok || item.Namespace == "" {
+			named[item.Name] = namedStmt{item: item, st: st}
+		}
+	}
+
+	for _, rt := range conf.Routes {
+		v, ok := named[rt.Query]
+		if !ok {
+			continue
+		}
+
+		rp, pnames := openAPIRoutePath(rt.Path)
+		p, ok := d.Paths[rp]
+		if !ok {
+			p = &openAPIPath{}
+			d.Paths[rp] = p
+		}
+		op := newOpenAPIRouteOperation(rt, pnames, v.item, v.st)
+
+		switch strings.ToUpper(rt.Method) {
+		case "GET":
+			p.Get = op
+		case "POST":
+			p.Post = op
+		case "PUT":
+			p.Put = op
+		case "PATCH":
+			p.Patch = op
+		case "DELETE":
+			p.Delete = op
+		}
+	}
+
+	return json.Marshal(d)
+}
+
+// compileItem compiles a query from the allow list for the role in the context
+func (gj *graphjinEngine) compileItem(c context.Context, item allow.Item) (st stmt, err error) {
+	r := gj.newGraphqlReq(nil, "", item.Name, nil, nil)
+	r.Set(item)
+
+	if r.operation == qcode.QTSubscription {
+		err = fmt.Errorf("subscriptions are not supported: %s", item.Name)
+		return
+	}
+
+	s, err := newGState(c, gj, r)
+	if err != nil {
+		return
+	}
+
+	if err = s.compileQueryForRole(); err != nil {
+		return
+	}
+	st = s.cs.st
+	return
+}
+
+// newOpenAPIPath returns the path item for a named query, queries can
+// be called using GET and POST while mutations only using POST
+func newOpenAPIPath(name string, item allow.Item, st stmt) *openAPIPath {
+	vars := newVarsSchema(st.qc, st.md)
+	res := openAPIResponse{
+		Description: "Result",
+		Content: map[string]openAPIMedia{
+			"application/json": {Schema: newResultSchema(st.qc)},
+		},
+	}
+
+	op := func(method string) *openAPIOperation {
+		return &openAPIOperation{
+			OperationID: strings.ReplaceAll(name, ".", "_") + method,
+			Summary:     item.Name,
+			Tags:        []string{item.Operation},
+			Responses:   map[string]openAPIResponse{"200": res},
+		}
+	}
+
+	p := &openAPIPath{}
+
+	p.Post = op("Post")
+	p.Post.RequestBody = &openAPIBody{
+		Required: len(vars.Required) != 0,
+		Content:  map[string]openAPIMedia{"application/json": {Schema: vars}},
+	}
+
+	if st.qc.SType != qcode.QTQuery {
+		return p
+	}
+
+	p.Get = op("Get")
+	p.Get.Parameters = []openAPIParam{{
+		Name:     "variables",
+		In:       "query",
+		Required: len(vars.Required) != 0,
+		Content:  map[string]openAPIMedia{"application/json": {Schema: vars}},
+	}}
+	return p
+}
+
+// newOpenAPIRouteOperation returns the operation for a route mapped to a
+// named query, the variables not bound to the path, query string or headers
+// are taken from the request body
+func newOpenAPIRouteOperation(rt OpenAPIRoute, pnames []string, item allow.Item, st stmt) *openAPIOperation {
+	method := strings.ToUpper(rt.Method)
+	vars := newVarsSchema(st.qc, st.md)
+
+	op := &openAPIOperation{
+		OperationID: openAPIRouteID(method, rt.Path),
+		Summary:     rt.Query,
+		Tags:        []string{item.Operation},
+		Responses:   newRouteResponses(method, st.qc),
+	}
+
+	bound := make(map[string]bool)
+	param := func(name, in, v string, required bool) {
+		bound[v] = true
+		op.Parameters = append(op.Parameters, openAPIParam{
+			Name:     name,
+			In:       in,
+			Required: required,
+			Schema:   newVarSchema(vars, v),
+		})
+	}
+
+	for _, n := range pnames {
+		param(n, "path", n, true)
+	}
+
+	// required variables can only be left out of the query string and
+	// headers when the request has a body to set them
+	hasBody := method != "GET"
+
+	for _, k := range sortedKeys(rt.Params) {
+		v := rt.Params[k]
+		param(k, "query", v, !hasBody && isRequiredVar(vars, v))
+	}
+	for _, k := range sortedKeys(rt.Headers) {
+		v := rt.Headers[k]
+		param(k, "header", v, !hasBody && isRequiredVar(vars, v))
+	}
+
+	if !hasBody {
+		return op
+	}
+
+	var body *openAPISchema
+	var required bool
+
+	if rt.Body != "" {
+		body = newVarSchema(vars, rt.Body)
+		required = isRequiredVar(vars, rt.Body)
+	} else {
+		body = &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+		for k, v := range vars.Properties {
+			if !bound[k] {
+				body.Properties[k] = v
+			}
+		}
+		for _, k := range vars.Required {
+			if !bound[k] {
+				body.Required = append(body.Required, k)
+			}
+		}
+		required = len(body.Required) != 0
+	}
+
+	op.RequestBody = &openAPIBody{
+		Required: required,
+		Content:  map[string]openAPIMedia{"application/json": {Schema: body}},
+	}
+	return op
+}
+
+// newRouteResponses returns the responses of a route, the status codes
+// are the ones set by the REST route handler
+func newRouteResponses(method string, qc *qcode.QCode) map[string]openAPIResponse {
+	errRes := func(desc string) openAPIResponse {
+		return openAPIResponse{
+			Description: desc,
+			Content: map[string]openAPIMedia{
+				"application/json": {Schema: &openAPISchema{
+					Type: "object",
+					Properties: map[string]*openAPISchema{
+						"errors": {Type: "array", Items: &openAPISchema{Type: "string"}},
+					},
+				}},
+			},
+		}
+	}
+
+	code := http.StatusOK
+	if method == "POST" && qc.SType != qcode.QTQuery {
+		code = http.StatusCreated
+	}
+
+	return map[string]openAPIResponse{
+		fmt.Sprint(code): {
+			Description: "Result",
+			Content: map[string]openAPIMedia{
+				"application/json": {Schema: newResultSchema(qc)},
+			},
+		},
+		"400": errRes("Missing or invalid variable"),
+		"401": errRes("Unauthorized"),
+		"404": errRes("Not found"),
+		"422": errRes("Validation failed"),
+		"500": errRes("Internal error"),
+	}
+}
+
+// openAPIRoutePath returns the route path with the patterns removed from the
+// parameters ({id:[0-9]+} becomes {id}) along with the parameter names
+func openAPIRoutePath(p string) (string, []string) {
+	var names []string
+
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		if len(seg) < 2 || seg[0] != '{' || seg[len(seg)-1] != '}' {
+			continue
+		}
+		name := seg[1 : len(seg)-1]
+		if n := strings.IndexByte(name, ':'); n != -1 {
+			name = name[:n]
+		}
+		segs[i] = "{" + name + "}"
+		names = append(names, name)
+	}
+	return strings.Join(segs, "/"), names
+}
+
+// openAPIRouteID returns the operation id of a route (GET /users/{id} becomes getUsersId)
+func openAPIRouteID(method, p string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+
+	rp, _ := openAPIRoutePath(p)
+	for _, s := range strings.FieldsFunc(rp, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_')
+	}) {
+		sb.WriteString(strings.ToUpper(s[:1]) + s[1:])
+	}
+	return sb.String()
+}
+
+// newVarSchema returns the schema of a variable, variables not used by
+// the query are left untyped
+func newVarSchema(vars *openAPISchema, name string) *openAPISchema {
+	if v, ok := vars.Properties[name]; ok {
+		return v
+	}
+	return &openAPISchema{}
+}
+
+// isRequiredVar returns true if the variable is required by the query
+func isRequiredVar(vars *openAPISchema, name string) bool {
+	for _, v := range vars.Required {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// newVarsSchema returns the schema of the variables used by the query
+func newVarsSchema(qc *qcode.QCode, md psql.Metadata) *openAPISchema {
+	sc := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+
+	for _, v := range queryVars(qc, md) {
+		ps := newGQLTypeSchema(v.Type, v.Array)
+		ps.Default = v.Default
+
+		if v.Required {
+			sc.Required = append(sc.Required, v.Name)
+		} else {
+			ps.Nullable = true
+		}
+		sc.Properties[v.Name] = ps
+	}
+	return sc
+}
+
+// newResultSchema returns the schema of the response
+func newResultSchema(qc *qcode.QCode) *openAPISchema {
+	data := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+
+	for _, id := range qc.Roots {
+		addSelectSchema(qc, &qc.Selects[id], data)
+	}
+
+	return &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"data": data,
+			"errors": {
+				Type: "array",
+				Items: &openAPISchema{
+					Type: "object",
+					Properties: map[string]*openAPISchema{
+						"message": {Type: "string"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// addSelectSchema adds the schema of a selector to the object schema of its parent
+func addSelectSchema(qc *qcode.QCode, sel *qcode.Select, parent *openAPISchema) {
+	switch sel.SkipRender {
+	case qcode.SkipTypeDrop:
+		return
+
+	case qcode.SkipTypeRemote:
+		parent.Properties[sel.FieldName] = &openAPISchema{Nullable: true}
+		return
+	}
+
+	if sel.Paging.Cursor {
+		parent.Properties[sel.FieldName+"_cursor"] = &openAPISchema{Type: "string", Nullable: true}
+	}
+
+	var sc *openAPISchema
+
+	if sel.SkipRender != qcode.SkipTypeNone {
+		sc = &openAPISchema{Type: "object", Nullable: true}
+	} else {
+		sc = newSelectSchema(qc, sel)
+	}
+
+	if !sel.Singular {
+		sc = &openAPISchema{Type: "array", Items: sc}
+	}
+	sc.Nullable = true
+	parent.Properties[sel.FieldName] = sc
+}
+
+// newSelectSchema returns the object schema of a selector
+func newSelectSchema(qc *qcode.QCode, sel *qcode.Select) *openAPISchema {
+	sc := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+
+	if sel.Type == qcode.SelTypeUnion {
+		sc.Properties = nil
+		for _, id := range sel.Children {
+			csel := &qc.Selects[id]
+			if csel.Type == qcode.SelTypeMember {
+				sc.OneOf = append(sc.OneOf, newSelectSchema(qc, csel))
+			}
+		}
+		return sc
+	}
+
+	if sel.Typename {
+		sc.Properties["__typename"] = &openAPISchema{Type: "string"}
+	}
+
+	for _, f := range sel.Fields {
+		if f.SkipRender == qcode.SkipTypeDrop {
+			continue
+		}
+
+		var fs *openAPISchema
+		switch f.Type {
+		case qcode.FieldTypeCol:
+			fs = newColumnSchema(f.Col)
+		case qcode.FieldTypeFunc:
+			fs = newDBTypeSchema(f.Func.Type, false)
+			fs.Nullable = true
+		default:
+			continue
+		}
+
+		if f.SkipRender != qcode.SkipTypeNone {
+			fs.Nullable = true
+		}
+		sc.Properties[f.FieldName] = fs
+	}
+
+	for _, id := range sel.Children {
+		addSelectSchema(qc, &qc.Selects[id], sc)
+	}
+	return sc
+}
+
+// newColumnSchema returns the schema of a table column
+func newColumnSchema(col sdata.DBColumn) *openAPISchema {
+	sc := newDBTypeSchema(col.Type, col.Array)
+	sc.Description = col.Comment
+	sc.Nullable = !col.NotNull
+	return sc
+}
+
+// newDBTypeSchema returns the schema of a database type
+func newDBTypeSchema(dbType string, array bool) *openAPISchema {
+	t, list := getType(dbType)
+	return newGQLTypeSchema(t, array || list)
+}
+
+// newGQLTypeSchema returns the schema of a GraphQL type
+func newGQLTypeSchema(t string, array bool) *openAPISchema {
+	var sc *openAPISchema
+
+	switch t {
+	case "Int":
+		sc = &openAPISchema{Type: "integer"}
+	case "Float":
+		sc = &openAPISchema{Type: "number"}
+	case "Boolean":
+		sc = &openAPISchema{Type: "boolean"}
+	case "JSON":
+		sc = &openAPISchema{}
+	default:
+		sc = &openAPISchema{Type: "string"}
+	}
+
+	if array {
+		sc = &openAPISchema{Type: "array", Items: sc}
+	}
+	return sc
+}
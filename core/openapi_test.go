@@ -0,0 +1,197 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	_log "log"
+	"strings"
+	"testing"
+
+	"github.com/dosco/graphjin/core/v3/internal/allow"
+	"github.com/dosco/graphjin/core/v3/internal/sdata"
+)
+
+func TestOpenAPI(t *testing.T) {
+	g := newTestAllowListGraphJin(t)
+
+	var logs bytes.Buffer
+	g.Load().(*graphjinEngine).log = _log.New(&logs, "", 0)
+
+	c := context.WithValue(context.Background(), UserIDKey, 1)
+	b, err := g.OpenAPI(c, OpenAPIConfig{BasePath: "/api/v1/rest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(b), "getMissing") {
+		t.Errorf("expected the query that failed to compile to be left out: %s", b)
+	}
+	if !strings.Contains(logs.String(), "getMissing") {
+		t.Errorf("expected the query that failed to compile to be logged: %q", logs.String())
+	}
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	qp := doc.Paths["/api/v1/rest/getProducts"]
+	if qp == nil || qp.Get == nil || qp.Post == nil {
+		t.Fatalf("expected get and post for getProducts: %s", b)
+	}
+
+	vars := qp.Post.RequestBody.Content["application/json"].Schema
+	if v := vars.Properties["price"]; v == nil || v.Type != "number" {
+		t.Errorf("expected price variable of type number: %s", b)
+	}
+	if v := vars.Properties["limit"]; v == nil || v.Type != "integer" || string(v.Default) != "10" {
+		t.Errorf("expected limit variable with a default of 10: %s", b)
+	}
+
+	data := qp.Get.Responses["200"].Content["application/json"].Schema.Properties["data"]
+	products := data.Properties["products"]
+	if products == nil || products.Type != "array" {
+		t.Fatalf("expected products to be an array: %s", b)
+	}
+	if v := products.Items.Properties["id"]; v == nil || v.Type != "integer" {
+		t.Errorf("expected products.id of type integer: %s", b)
+	}
+	if v := products.Items.Properties["user"]; v == nil || v.Type != "object" ||
+		v.Properties["full_name"] == nil {
+		t.Errorf("expected products.user object with full_name: %s", b)
+	}
+
+	mp := doc.Paths["/api/v1/rest/createProduct"]
+	if mp == nil || mp.Get != nil || mp.Post == nil {
+		t.Fatalf("expected only post for createProduct: %s", b)
+	}
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	g := newTestAllowListGraphJin(t)
+
+	c := context.WithValue(context.Background(), UserIDKey, 1)
+	b, err := g.OpenAPI(c, OpenAPIConfig{
+		BasePath: "/api/v1/rest",
+		Routes: []OpenAPIRoute{
+			{
+				Method:  "GET",
+				Path:    "/products/{price:[0-9.]+}",
+				Query:   "getProducts",
+				Params:  map[string]string{"n": "limit"},
+				Headers: map[string]string{"X-Limit": "limit"},
+			},
+			{Method: "POST", Path: "/products", Query: "createProduct", Body: "data"},
+			{Method: "GET", Path: "/missing", Query: "missing"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Paths["/missing"] != nil {
+		t.Errorf("expected no path for a route to an unknown query: %s", b)
+	}
+
+	gp := doc.Paths["/products/{price}"]
+	if gp == nil || gp.Get == nil || gp.Post != nil {
+		t.Fatalf("expected only get for /products/{price}: %s", b)
+	}
+
+	in := make(map[string]openAPIParam)
+	for _, v := range gp.Get.Parameters {
+		in[v.In] = v
+	}
+	if v := in["path"]; v.Name != "price" || !v.Required || v.Schema.Type != "number" {
+		t.Errorf("expected a required price path parameter of type number: %s", b)
+	}
+	if v := in["query"]; v.Name != "n" || v.Schema.Type != "integer" {
+		t.Errorf("expected a n query parameter of type integer: %s", b)
+	}
+	if v := in["header"]; v.Name != "X-Limit" || v.Schema.Type != "integer" {
+		t.Errorf("expected a X-Limit header parameter of type integer: %s", b)
+	}
+	for _, code := range []string{"200", "400", "401", "404", "422", "500"} {
+		if _, ok := gp.Get.Responses[code]; !ok {
+			t.Errorf("expected a %s response: %s", code, b)
+		}
+	}
+
+	pp := doc.Paths["/products"]
+	if pp == nil || pp.Post == nil || pp.Post.RequestBody == nil {
+		t.Fatalf("expected post with a body for /products: %s", b)
+	}
+	if _, ok := pp.Post.Responses["201"]; !ok {
+		t.Errorf("expected a 201 response for a mutation: %s", b)
+	}
+}
+
+func newTestAllowListGraphJin(t *testing.T) *GraphJin {
+	di := sdata.GetTestDBInfo()
+	schema, err := sdata.NewDBSchema(di, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewOsFS(t.TempDir())
+	queries := map[string]string{
+		"getProducts": `query getProducts($limit = 10) {
+			products(limit: $limit, where: { price: { gt: $price } }) {
+				id
+				name
+				user {
+					full_name
+				}
+			}
+		}`,
+		"getMissing": `query getMissing {
+			missing_table {
+				id
+			}
+		}`,
+		"createProduct": `mutation createProduct {
+			products(insert: $data) {
+				id
+			}
+		}`,
+	}
+	err = fs.Put("/queries/createProduct.json", []byte(`{"data": {"name": "", "price": 1.5}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, q := range queries {
+		if err := fs.Put("/queries/"+name+".gql", []byte(q)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gj := &graphjinEngine{
+		log:    _log.New(io.Discard, "", 0),
+		schema: schema,
+		fs:     fs,
+		trace:  &tracer{},
+		conf:   &Config{DBType: "postgres"},
+		roles: map[string]*Role{
+			"user": {Name: "user"},
+			"anon": {Name: "anon"},
+		},
+	}
+	if err := gj.initCompilers(); err != nil {
+		t.Fatal(err)
+	}
+	if gj.allowList, err = allow.New(nil, fs, true); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &GraphJin{}
+	g.Store(gj)
+	return g
+}
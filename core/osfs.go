@@ -42,6 +42,24 @@ func (f *osFS) Exists(path string) (ok bool, err error) {
 	return
 }
 
+// List returns the names of the files in the folder
+func (f *osFS) List(path string) (names []string, err error) {
+	de, err := os.ReadDir(filepath.Join(f.basePath, path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+
+	for _, e := range de {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return
+}
+
 // Remove deletes the file
 func (f *osFS) exists(path string) (ok bool, err error) {
 	if _, err = os.Stat(path); err == nil {
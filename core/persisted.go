@@ -0,0 +1,45 @@
+package core
+
+import (
+	"github.com/dosco/graphjin/core/v3/internal/allow"
+)
+
+// ImportPersistedQueries adds the queries of a persisted query manifest to
+// the allow list and returns their names. Both the Apollo manifest format and
+// a json object of hashes and queries (Relay) are supported. The imported
+// queries can then be used by sending the hash of the query as the APQKey
+// in the request config instead of the query.
+func (g *GraphJin) ImportPersistedQueries(manifest []byte) (names []string, err error) {
+	gj := g.Load().(*graphjinEngine)
+
+	queries, err := allow.ParseManifest(manifest)
+	if err != nil {
+		return
+	}
+	return gj.allowList.Import(queries, gj.namespace)
+}
+
+// PersistedOperation returns the operation type and name of the query saved
+// under an APQ key, either a persisted query imported into the allow list or a
+// query in the APQ cache. The header is empty when the key is not known.
+func (g *GraphJin) PersistedOperation(key string) (h Header, err error) {
+	gj := g.Load().(*graphjinEngine)
+
+	var query []byte
+
+	item, err := gj.allowList.GetByHash(key)
+	switch {
+	case err == nil:
+		query = item.Query
+	case err == allow.ErrUnknownPersistedQuery:
+		query, _ = gj.cache.Get(APQ_PX + key)
+		err = nil
+	default:
+		return
+	}
+
+	if len(query) != 0 {
+		h, err = Operation(string(query))
+	}
+	return
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dosco/graphjin/core/v3/internal/allow"
+)
+
+func TestImportPersistedQueries(t *testing.T) {
+	g := newTestAllowListGraphJin(t)
+	gj := g.Load().(*graphjinEngine)
+
+	relay := `{
+		"a1": "query getUser { users(id: 1) { id } }",
+		"b2": "query getUser { users(id: 1) { id } }"
+	}`
+
+	apollo := `{
+		"format": "apollo-persisted-query-manifest",
+		"version": 1,
+		"operations": [{
+			"id": "c3",
+			"name": "getUsers",
+			"type": "query",
+			"body": "fragment User on users { id } query getUsers { users { ...User } }"
+		}]
+	}`
+
+	names, err := g.ImportPersistedQueries([]byte(relay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"getUser"}) {
+		t.Fatalf("expected [getUser] got %v", names)
+	}
+
+	if _, err := g.ImportPersistedQueries([]byte(apollo)); err != nil {
+		t.Fatal(err)
+	}
+
+	for hash, name := range map[string]string{"a1": "getUser", "b2": "getUser", "c3": "getUsers"} {
+		item, err := gj.allowList.GetByHash(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.Name != name {
+			t.Errorf("expected query %s for hash %s got %s", name, hash, item.Name)
+		}
+	}
+
+	if _, err := gj.allowList.GetByHash("d4"); err != allow.ErrUnknownPersistedQuery {
+		t.Errorf("expected an unknown persisted query error got %v", err)
+	}
+
+	// the hashes are read back from the filesystem
+	al, err := allow.New(nil, gj.fs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item, err := al.GetByHash("c3"); err != nil || item.Name != "getUsers" {
+		t.Errorf("expected query getUsers for hash c3 got %s: %v", item.Name, err)
+	}
+
+	_, err = g.ImportPersistedQueries([]byte(`{"e5": "query getUser { users { id } }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = g.ImportPersistedQueries([]byte(`{"f6": "{ users { id } }"}`))
+	if err == nil {
+		t.Error("expected an error for a query without a name")
+	}
+}
+
+func TestStrictPersistedQueries(t *testing.T) {
+	g := newTestAllowListGraphJin(t)
+	gj := g.Load().(*graphjinEngine)
+
+	gj.prodSec = true
+	gj.conf.StrictPersistedQueries = true
+
+	_, err := g.GraphQL(context.Background(), "query getProducts { products { id } }", nil, nil)
+	if !errors.Is(err, ErrPersistedQueryNotFound) {
+		t.Errorf("expected a persisted query not found error got %v", err)
+	}
+
+	rc := &RequestConfig{APQKey: "unknown"}
+	_, err = g.GraphQL(context.Background(), "query getProducts { products { id } }", nil, rc)
+	if !errors.Is(err, ErrPersistedQueryNotFound) {
+		t.Errorf("expected a persisted query not found error got %v", err)
+	}
+
+	_, err = g.Subscribe(context.Background(), "subscription getProducts { products { id } }", nil, nil)
+	if !errors.Is(err, ErrPersistedQueryNotFound) {
+		t.Errorf("expected a persisted query not found error got %v", err)
+	}
+
+	_, err = g.Subscribe(context.Background(), "subscription getProducts { products { id } }", nil, rc)
+	if !errors.Is(err, ErrPersistedQueryNotFound) {
+		t.Errorf("expected a persisted query not found error got %v", err)
+	}
+}
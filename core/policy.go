@@ -0,0 +1,258 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dosco/graphjin/core/v3/internal/qcode"
+)
+
+// Policy is used to authorize operations with rules that are evaluated
+// in-process (eg. CEL or Rego). It's called with the compiled operation
+// on every request after the role config has been applied.
+type Policy interface {
+	Evaluate(c context.Context, in PolicyInput) (PolicyResult, error)
+}
+
+// PolicyInput is the compiled operation that is checked by the policy
+type PolicyInput struct {
+	// Type of operation: query, subscription, insert, update, upsert or delete
+	Type string
+
+	// Name of the operation
+	Name string
+
+	// Role of the user
+	Role string
+
+	// ID of the user, nil for anonymous users
+	UserID interface{}
+
+	// Scopes granted to the user
+	Scopes []string
+
+	// Claims of the user's auth token
+	Claims map[string]interface{}
+
+	// Variables of the operation
+	Vars map[string]interface{}
+
+	// Tables used by the operation
+	Tables []PolicyTable
+}
+
+// PolicyTable is a table used by the operation
+type PolicyTable struct {
+	Name   string
+	Schema string
+
+	// Type of operation on the table: query, insert, update, upsert or delete
+	Type string
+
+	// Columns selected or set by the operation
+	Columns []string
+}
+
+// PolicyResult is the decision of the policy
+type PolicyResult struct {
+	// Deny the operation
+	Deny bool
+
+	// Reason the operation was denied
+	Reason string
+
+	// Filters added to the where clause of the tables, the key is the table name
+	// Example: { "orders": ["{ org_id: { eq: $org_id } }"] }
+	Filters map[string][]string
+
+	// Values of the variables used by the filters, these must come from a
+	// trusted source like the claims of the user. The variables used by the
+	// filters cannot be set by the client.
+	// Example: { "org_id": 5 }
+	Vars map[string]interface{}
+}
+
+// OptionSetPolicy sets the policy used to authorize operations
+func OptionSetPolicy(p Policy) Option {
+	return func(s *graphjinEngine) error {
+		s.policy = p
+		return nil
+	}
+}
+
+// evalPolicy checks the compiled query against the policy, when the policy
+// adds filters the query is compiled again for this request only
+func (s *gstate) evalPolicy(c context.Context) (err error) {
+	st := s.cs.st
+
+	in, err := s.policyInput(c, st.qc)
+	if err != nil {
+		return
+	}
+
+	res, err := s.gj.policy.Evaluate(c, in)
+	if err != nil {
+		return
+	}
+
+	if res.Deny {
+		if res.Reason != "" {
+			return fmt.Errorf("denied by policy: %s", res.Reason)
+		}
+		return errors.New("denied by policy")
+	}
+
+	if len(res.Filters) == 0 {
+		return
+	}
+
+	if st.qc, err = s.gj.qcodeCompiler.AddFilters(st.qc, res.Filters); err != nil {
+		return fmt.Errorf("policy filter: %w", err)
+	}
+
+	if err = s.setPolicyVars(st.qc, res); err != nil {
+		return fmt.Errorf("policy filter: %w", err)
+	}
+
+	if err = s.compileStmt(&st); err != nil {
+		return
+	}
+	s.cs = &cstate{st: st}
+	s.pkey = policyKey(res.Filters)
+	return
+}
+
+// setPolicyVars sets the variables used by the policy filters to the values
+// from the policy, the client is not allowed to set any of them
+func (s *gstate) setPolicyVars(qc *qcode.QCode, res PolicyResult) error {
+	vars, err := qcode.FilterVars(res.Filters)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range vars {
+		if isContextVar(name) {
+			continue
+		}
+
+		if _, ok := s.vmap[name]; ok {
+			return fmt.Errorf("variable '%s' cannot be set by the client", name)
+		}
+		for _, v := range qc.Vars {
+			if v.Name == name {
+				return fmt.Errorf("variable '%s' cannot be set by the query", name)
+			}
+		}
+
+		val, ok := res.Vars[name]
+		if !ok {
+			return fmt.Errorf("variable '%s' not set by the policy", name)
+		}
+
+		b, err := json.Marshal(val)
+		if err != nil {
+			return err
+		}
+		if s.vmap == nil {
+			s.vmap = make(map[string]json.RawMessage, len(vars))
+		}
+		s.vmap[name] = b
+	}
+	return nil
+}
+
+// policyInput returns the input for the policy from the compiled query
+func (s *gstate) policyInput(c context.Context, qc *qcode.QCode) (in PolicyInput, err error) {
+	in = PolicyInput{
+		Name:   qc.Name,
+		Role:   s.role,
+		UserID: c.Value(UserIDKey),
+		Scopes: s.scopes,
+	}
+
+	if qc.Type == qcode.QTMutation {
+		in.Type = policyType(qc.SType)
+	} else {
+		in.Type = policyType(qc.Type)
+	}
+
+	if v, ok := c.Value(UserClaimsKey).(map[string]interface{}); ok {
+		in.Claims = v
+	}
+
+	if len(s.vmap) != 0 {
+		in.Vars = make(map[string]interface{}, len(s.vmap))
+		for k, v := range s.vmap {
+			var val interface{}
+			if err = json.Unmarshal(v, &val); err != nil {
+				return
+			}
+			in.Vars[k] = val
+		}
+	}
+
+	for _, sel := range qc.Selects {
+		if sel.SkipRender != qcode.SkipTypeNone || sel.Ti.Name == "" {
+			continue
+		}
+		t := PolicyTable{Name: sel.Ti.Name, Schema: sel.Ti.Schema, Type: policyType(qc.SType)}
+		for _, f := range sel.Fields {
+			if f.Type == qcode.FieldTypeCol && f.SkipRender == qcode.SkipTypeNone {
+				t.Columns = append(t.Columns, f.Col.Name)
+			}
+		}
+		in.Tables = append(in.Tables, t)
+	}
+
+	for _, m := range qc.Mutates {
+		qt := m.Type.QType()
+		if qt == qcode.QTUnknown {
+			continue
+		}
+		t := PolicyTable{Name: m.Ti.Name, Schema: m.Ti.Schema, Type: policyType(qt)}
+		for _, col := range m.Cols {
+			t.Columns = append(t.Columns, col.Col.Name)
+		}
+		in.Tables = append(in.Tables, t)
+	}
+	return
+}
+
+func policyType(qt qcode.QType) string {
+	switch qt {
+	case qcode.QTQuery:
+		return "query"
+	case qcode.QTSubscription:
+		return "subscription"
+	case qcode.QTInsert:
+		return "insert"
+	case qcode.QTUpdate:
+		return "update"
+	case qcode.QTUpsert:
+		return "upsert"
+	case qcode.QTDelete:
+		return "delete"
+	}
+	return ""
+}
+
+// policyKey returns a key for the filters added by the policy, subscriptions
+// are only shared by subscribers with the same filters
+func policyKey(filters map[string][]string) string {
+	var sb strings.Builder
+
+	tables := make([]string, 0, len(filters))
+	for k := range filters {
+		tables = append(tables, k)
+	}
+	sort.Strings(tables)
+
+	for _, k := range tables {
+		sb.WriteString(":" + k + "=" + strings.Join(filters[k], ","))
+	}
+	return sb.String()
+}
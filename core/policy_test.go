@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type testPolicy struct {
+	in  PolicyInput
+	res PolicyResult
+}
+
+func (p *testPolicy) Evaluate(c context.Context, in PolicyInput) (PolicyResult, error) {
+	p.in = in
+	return p.res, nil
+}
+
+func TestPolicy(t *testing.T) {
+	g := newTestAllowListGraphJin(t)
+	gj := g.Load().(*graphjinEngine)
+
+	p := &testPolicy{}
+	gj.policy = p
+
+	item, err := gj.allowList.GetByName("getProducts", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compile := func(vars json.RawMessage) (gstate, error) {
+		r := gj.newGraphqlReq(nil, "", item.Name, nil, vars)
+		r.Set(item)
+
+		c := context.WithValue(context.Background(), UserIDKey, 1)
+		c = context.WithValue(c, UserClaimsKey, map[string]interface{}{"org_id": 5})
+
+		s, err := newGState(c, gj, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s, s.compile(c)
+	}
+
+	newState := func() gstate {
+		s, err := compile(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	s := newState()
+	sql := s.sql()
+
+	if p.in.Type != "query" || p.in.Role != "user" || p.in.Claims["org_id"] != 5 {
+		t.Errorf("unexpected policy input: %+v", p.in)
+	}
+	if len(p.in.Tables) != 2 || p.in.Tables[0].Name != "products" ||
+		strings.Join(p.in.Tables[0].Columns, ",") != "id,name" {
+		t.Errorf("unexpected policy tables: %+v", p.in.Tables)
+	}
+
+	p.res = PolicyResult{Filters: map[string][]string{
+		"users": {"{ id: { eq: 42 } }"},
+	}}
+	s = newState()
+
+	if s.sql() == sql || !strings.Contains(s.sql(), "42") {
+		t.Errorf("expected the policy filter to be added: %s", s.sql())
+	}
+	if s.pkey == "" {
+		t.Error("expected a key for the policy filters")
+	}
+
+	// the variables of the filters are set by the policy
+	p.res = PolicyResult{
+		Filters: map[string][]string{"users": {"{ id: { eq: $org_id } }"}},
+		Vars:    map[string]interface{}{"org_id": 5},
+	}
+	s = newState()
+
+	if v := string(s.vmap["org_id"]); v != "5" {
+		t.Errorf("expected the variable to be set by the policy: %s", v)
+	}
+
+	// and cannot be forged by the client
+	_, err = compile(json.RawMessage(`{"org_id": 42}`))
+	if err == nil || err.Error() != "policy filter: variable 'org_id' cannot be set by the client" {
+		t.Errorf("expected the client variable to be rejected: %v", err)
+	}
+
+	p.res.Vars = nil
+	if _, err = compile(nil); err == nil ||
+		err.Error() != "policy filter: variable 'org_id' not set by the policy" {
+		t.Errorf("expected the missing variable to be rejected: %v", err)
+	}
+
+	p.res = PolicyResult{}
+	if s = newState(); s.sql() != sql {
+		t.Errorf("expected the compiled query not to be modified: %s", s.sql())
+	}
+
+	p.res = PolicyResult{Deny: true, Reason: "no products"}
+	r := gj.newGraphqlReq(nil, "", item.Name, nil, nil)
+	r.Set(item)
+
+	s, err = newGState(context.Background(), gj, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.compile(context.Background()); err == nil ||
+		err.Error() != "denied by policy: no products" {
+		t.Errorf("expected the policy to deny the query: %v", err)
+	}
+}
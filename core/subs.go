@@ -78,34 +78,62 @@ type Member struct {
 // Any database changes that apply to the query are streamed back in realtime.
 //
 // In developer mode all named queries are saved into the queries folder and in production mode only
-// queries from these saved queries can be used.
+// queries from these saved queries can be used. With strict persisted queries enabled the query
+// must be a persisted query set using the APQ key of the request config.
 func (g *GraphJin) Subscribe(
 	c context.Context,
 	query string,
 	vars json.RawMessage,
 	rc *RequestConfig,
 ) (m *Member, err error) {
+	gj := g.Load().(*graphjinEngine)
+
+	var item allow.Item
+	var persisted bool
+
+	// get query from the persisted queries imported into the allow list
+	if rc != nil && rc.APQKey != "" {
+		item, err = gj.allowList.GetByHash(rc.APQKey)
+		if err != nil && err != allow.ErrUnknownPersistedQuery {
+			return
+		}
+		persisted = (err == nil)
+		err = nil
+	}
+
+	// in strict mode only persisted queries can be used
+	if !persisted && gj.prodSec && gj.conf.StrictPersistedQueries {
+		err = ErrPersistedQueryNotFound
+		return
+	}
+
+	if persisted {
+		query = string(item.Query)
+	}
+
 	// get the name, query vars
 	h, err := graph.FastParse(query)
 	if err != nil {
 		return
 	}
 
-	gj := g.Load().(*graphjinEngine)
-
 	// create the request object
 	r := gj.newGraphqlReq(rc, "subscription", h.Name, nil, vars)
 
 	// if production security enabled then get query and metadata
 	// from allow list
-	if gj.prodSec {
-		var item allow.Item
+	switch {
+	case persisted:
+		r.Set(item)
+
+	case gj.prodSec:
 		item, err = gj.allowList.GetByName(h.Name, true)
 		if err != nil {
 			return
 		}
 		r.Set(item)
-	} else {
+
+	default:
 		r.query = []byte(query)
 	}
 
@@ -163,7 +191,13 @@ func (gj *graphjinEngine) subscribe(c context.Context, r GraphqlReq) (
 		}
 	}
 
-	k := s.key()
+	// the scopes and policy are checked for every subscriber, subscribers
+	// with different policy filters cannot share a subscription
+	if err = s.compile(c); err != nil {
+		return
+	}
+
+	k := s.key() + s.pkey
 	v, _ := gj.subs.LoadOrStore(k, &sub{
 		k:    k,
 		s:    s,
@@ -209,10 +243,6 @@ func (gj *graphjinEngine) subscribe(c context.Context, r GraphqlReq) (
 
 // initSub function is called on the graphjin struct to initialize a subscription.
 func (gj *graphjinEngine) initSub(c context.Context, sub *sub) (err error) {
-	if err = sub.s.compile(); err != nil {
-		return
-	}
-
 	if !gj.prod {
 		err = gj.saveToAllowList(sub.s.cs.st.qc, sub.s.r.namespace)
 		if err != nil {
@@ -535,6 +565,7 @@ func (gj *graphjinEngine) subNotifyMemberEx(sub *sub,
 		sql:       sub.s.cs.st.sql,
 		role:      sub.s.cs.st.role,
 		Data:      ejs,
+		Hash:      mm.dh,
 	}
 
 	// if parameters exists then each response is unique
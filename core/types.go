@@ -120,6 +120,38 @@ var dirTypes []dir = []dir{
 			atype: "tables" + SUFFIX_ENUM,
 		}},
 	},
+	{
+		name: "defer",
+		desc: "Deliver the fields of this fragment after the initial result",
+		locs: []string{LOC_FIELD, LOC_FRAG_SPREAD, LOC_INLINE_FRAG},
+		args: []dirArg{{
+			name:  "label",
+			desc:  "Label to identify the deferred result",
+			atype: "String",
+		}, {
+			name:  "if",
+			desc:  "Defer when true",
+			atype: "Boolean",
+		}},
+	},
+	{
+		name: "stream",
+		desc: "Deliver the items of this list after the initial result",
+		locs: []string{LOC_FIELD},
+		args: []dirArg{{
+			name:  "label",
+			desc:  "Label to identify the streamed result",
+			atype: "String",
+		}, {
+			name:  "if",
+			desc:  "Stream when true",
+			atype: "Boolean",
+		}, {
+			name:  "initialCount",
+			desc:  "Number of items to include in the initial result",
+			atype: "Int",
+		}},
+	},
 }
 
 type exp struct {
@@ -196,3 +228,14 @@ var expJSON = []exp{
 	{name: "containedIn", desc: "JSON value contains all of they key/value pairs"},
 	{name: "_containedIn", desc: "JSON value contains all of they key/value pairs"},
 }
+
+var expGeo = []exp{
+	{name: "st_dwithin", desc: "Geometry is within a distance of a point or geometry. Eg. { point: [lng, lat], distance: 1000 }"},
+	{name: "stDWithin", desc: "Geometry is within a distance of a point or geometry. Eg. { point: [lng, lat], distance: 1000 }"},
+	{name: "st_intersects", desc: "Geometry intersects a GeoJSON geometry"},
+	{name: "stIntersects", desc: "Geometry intersects a GeoJSON geometry"},
+	{name: "st_contains", desc: "Geometry contains a GeoJSON geometry"},
+	{name: "stContains", desc: "Geometry contains a GeoJSON geometry"},
+	{name: "st_bbox", desc: "Geometry overlaps a bounding box [min_lng, min_lat, max_lng, max_lat]"},
+	{name: "stBBox", desc: "Geometry overlaps a bounding box [min_lng, min_lat, max_lng, max_lat]"},
+}
@@ -10,6 +10,8 @@ import (
 	"net/http"
 	"sync/atomic"
 	"time"
+
+	"github.com/dosco/graphjin/auth/v3"
 )
 
 // adminDeployHandler handles the admin deploy endpoint
@@ -87,6 +89,67 @@ func adminRollbackHandler(s1 *HttpService) http.Handler {
 	return http.HandlerFunc(h)
 }
 
+// adminAPIKeysHandler handles the admin endpoints to create, rotate and revoke api keys
+func adminAPIKeysHandler(s1 *HttpService, action string) http.Handler {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		var req auth.APIKey
+		var res APIKeyResp
+		var err error
+
+		s := s1.Load().(*graphjinService)
+
+		if !s.isAdminSecret(r) {
+			authFail(w)
+			return
+		}
+
+		if s.apiKeys == nil {
+			badReq(w, "apikey auth is not enabled")
+			return
+		}
+
+		de := json.NewDecoder(r.Body)
+		if err := de.Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch {
+		case action == "create" && req.UserID == "":
+			badReq(w, "user_id is a required field")
+			return
+		case action != "create" && req.ID == "":
+			badReq(w, "id is a required field")
+			return
+		}
+
+		switch action {
+		case "create":
+			res.APIKey, res.Key, err = s.apiKeys.Create(r.Context(), req)
+		case "rotate":
+			res.ID = req.ID
+			res.Key, err = s.apiKeys.Rotate(r.Context(), req.ID)
+		case "revoke":
+			res.ID = req.ID
+			err = s.apiKeys.Revoke(r.Context(), req.ID)
+		}
+
+		if err == auth.ErrAPIKeyNotFound {
+			badReq(w, err.Error())
+			return
+		}
+		if err != nil {
+			intErr(w, fmt.Sprintf("api key error: %s", err.Error()))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	}
+
+	return http.HandlerFunc(h)
+}
+
 // adminConfigHandler handles the checking of the admin secret endpoint
 func (s *graphjinService) isAdminSecret(r *http.Request) bool {
 	atomic.AddInt32(&s.adminCount, 1)
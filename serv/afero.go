@@ -1,6 +1,8 @@
 package serv
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 
@@ -39,3 +41,21 @@ func (f *aferoFS) Put(path string, data []byte) (err error) {
 func (f *aferoFS) Exists(path string) (exists bool, err error) {
 	return afero.Exists(f.fs, path)
 }
+
+// List returns the names of the files in a folder
+func (f *aferoFS) List(path string) (names []string, err error) {
+	fi, err := afero.ReadDir(f.fs, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+
+	for _, v := range fi {
+		if !v.IsDir() {
+			names = append(names, v.Name())
+		}
+	}
+	return
+}
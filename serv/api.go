@@ -90,6 +90,11 @@ type graphjinService struct {
 	adminCount   int32
 	namespace    *string
 	tracer       trace.Tracer
+	sse          sseStreams
+	rlStore      RateLimitStore
+	apiKeys      *auth.APIKeyStore
+	login        *auth.LoginStore
+	codeSender   auth.CodeSender
 }
 
 type Option func(*graphjinService) error
@@ -207,10 +212,25 @@ func newGraphJinService(conf *Config, db *sql.DB, options ...Option) (*graphjinS
 	initLogLevel(s)
 	validateConf(s)
 
+	if s.rlStore == nil {
+		s.rlStore = newMemoryRateLimitStore()
+	}
+
 	if err := s.initDB(); err != nil {
 		return nil, err
 	}
 
+	if ac, ok := conf.Auth.FindType("apikey"); ok && s.db != nil {
+		s.apiKeys, err = auth.NewAPIKeyStore(s.db, conf.DBType, ac.APIKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.initLogin(); err != nil {
+		return nil, err
+	}
+
 	if s.deployActive {
 		err = s.hotStart()
 	} else {
@@ -235,6 +255,14 @@ func (s *graphjinService) normalStart() error {
 		opts = append(opts, core.OptionSetNamespace(*s.namespace))
 	}
 
+	if len(s.conf.Policies) != 0 {
+		p, err := newCELPolicy(s.conf.Policies)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, core.OptionSetPolicy(p))
+	}
+
 	var err error
 	s.gj, err = core.NewGraphJin(&s.conf.Core, s.db, opts...)
 	return err
@@ -281,6 +309,14 @@ func (s *graphjinService) hotStart() error {
 			core.OptionSetNamespace(*s.namespace))
 	}
 
+	if len(s.conf.Policies) != 0 {
+		p, err := newCELPolicy(s.conf.Policies)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, core.OptionSetPolicy(p))
+	}
+
 	s.gj, err = core.NewGraphJin(&s.conf.Core, s.db, opts...)
 	return err
 }
@@ -379,6 +415,22 @@ func (s *HttpService) RESTWithNS(ah auth.HandlerFunc, ns string) http.Handler {
 	return s.apiHandler(&ns, ah, true)
 }
 
+// OpenAPI is the http handler the OpenAPI document of the REST endpoint
+func (s *HttpService) OpenAPI(ah auth.HandlerFunc) http.Handler {
+	return apiV1Handler(s, nil, s.apiV1OpenAPI(), ah)
+}
+
+// Export is the http handler the export endpoint that streams the rows
+// of a named query as NDJSON or CSV
+func (s *HttpService) Export(ah auth.HandlerFunc) http.Handler {
+	return apiV1Handler(s, nil, s.apiV1Export(nil), ah)
+}
+
+// ExportWithNS is the http handler the namespaced export endpoint
+func (s *HttpService) ExportWithNS(ah auth.HandlerFunc, ns string) http.Handler {
+	return apiV1Handler(s, &ns, s.apiV1Export(&ns), ah)
+}
+
 func (s *HttpService) apiHandler(ns *string, ah auth.HandlerFunc, rest bool) http.Handler {
 	var h http.Handler
 	if rest {
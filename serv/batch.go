@@ -0,0 +1,175 @@
+package serv
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dosco/graphjin/core/v3"
+)
+
+const batchTxHeader = "X-Batch-Transaction"
+
+var errBatchRolledBack = errors.New("batch transaction rolled back")
+
+// apiV1Batch executes a batch of graphql requests and renders
+// an array of results in the same order as the requests
+func (s *graphjinService) apiV1Batch(c context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	batch []gqlReq,
+	ns *string,
+) {
+	if len(batch) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		renderErr(w, errors.New("batch is empty"))
+		return
+	}
+
+	if max := s.conf.Batch.MaxOperations; max > 0 && len(batch) > max {
+		w.WriteHeader(http.StatusBadRequest)
+		renderErr(w, fmt.Errorf("batch exceeds the limit of %d operations", max))
+		return
+	}
+
+	// the rate limiter has already counted this request once
+	if !s.rateLimit(w, r, "", len(batch)-1) {
+		return
+	}
+
+	for _, req := range batch {
+		if !s.rateLimitOp(w, r, s.opName(req)) {
+			return
+		}
+	}
+
+	var res []*core.Result
+	var err error
+
+	if strings.EqualFold(r.Header.Get(batchTxHeader), "true") {
+		res, err = s.batchTx(c, r, batch, ns)
+	} else {
+		res = s.batch(c, r, batch, ns)
+	}
+
+	if err != nil {
+		renderErr(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		renderErr(w, err)
+	}
+}
+
+// batch executes the requests concurrently
+func (s *graphjinService) batch(c context.Context,
+	r *http.Request,
+	batch []gqlReq,
+	ns *string,
+) []*core.Result {
+	res := make([]*core.Result, len(batch))
+
+	n := s.conf.Batch.Concurrency
+	if n <= 0 {
+		n = 1
+	}
+	sem := make(chan struct{}, n)
+
+	var wg sync.WaitGroup
+	for i := range batch {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			res[i], _ = s.batchOp(c, r, batch[i], ns, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	return res
+}
+
+// batchTx executes the requests one after the other within a single
+// database transaction, the transaction is rolled back on the first error
+func (s *graphjinService) batchTx(c context.Context,
+	r *http.Request,
+	batch []gqlReq,
+	ns *string,
+) ([]*core.Result, error) {
+	if s.db == nil {
+		return nil, errors.New("batch transactions need a database connection")
+	}
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	res := make([]*core.Result, len(batch))
+
+	for i := range batch {
+		if res[i], err = s.batchOp(c, r, batch[i], ns, tx); err == nil {
+			continue
+		}
+		for j := range res {
+			if j != i {
+				res[j] = errResult(errBatchRolledBack)
+			}
+		}
+		return res, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// batchOp executes a single request from the batch
+func (s *graphjinService) batchOp(c context.Context,
+	r *http.Request,
+	req gqlReq,
+	ns *string,
+	tx *sql.Tx,
+) (*core.Result, error) {
+	start := time.Now()
+
+	if h, _ := core.Operation(req.Query); h.Type == core.OpSubscription {
+		err := errors.New("subscriptions cannot be batched")
+		return errResult(err), err
+	}
+
+	rc := s.newRequestConfig(r, req, ns)
+	rc.Tx = tx
+
+	res, err := s.gj.GraphQL(c, req.Query, req.Vars, &rc)
+	if res == nil {
+		if err == nil {
+			err = errors.New("no result")
+		}
+		return errResult(err), err
+	}
+
+	if s.hook != nil {
+		s.hook(res)
+	}
+
+	if s.logLevel >= logLevelInfo {
+		s.reqLog(c, res, rc, time.Since(start).Milliseconds(), err)
+	}
+	return res, err
+}
+
+// errResult returns a result holding just the error
+func errResult(err error) *core.Result {
+	return &core.Result{Errors: []core.Error{{Message: err.Error()}}}
+}
@@ -0,0 +1,38 @@
+package serv
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	conf := "app_name: test\nbatch:\n  max_operations: 2\n  concurrency: 3\n"
+	afero.WriteFile(fs, "/dev.yml", []byte(conf), 0o666) //nolint:errcheck
+
+	c, err := ReadInConfigFS("/dev.yml", fs)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.Batch.MaxOperations)
+	assert.Equal(t, 3, c.Batch.Concurrency)
+
+	s := &graphjinService{conf: c}
+	r := httptest.NewRequest("POST", "/api/v1/graphql", nil)
+
+	w := httptest.NewRecorder()
+	s.apiV1Batch(r.Context(), w, r, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	s.apiV1Batch(r.Context(), w, r, make([]gqlReq, 3), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "limit of 2 operations")
+
+	req := gqlReq{Query: "subscription { users { id } }"}
+	res, err := s.batchOp(r.Context(), r, req, nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, "subscriptions cannot be batched", res.Errors[0].Message)
+}
@@ -13,12 +13,16 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/dosco/graphjin/auth/v3"
 	"github.com/go-resty/resty/v2"
 )
 
 const (
-	DeployRoute   = "/api/v1/deploy"
-	RollbackRoute = "/api/v1/deploy/rollback"
+	DeployRoute        = "/api/v1/deploy"
+	RollbackRoute      = "/api/v1/deploy/rollback"
+	APIKeysRoute       = "/api/v1/admin/api_keys"
+	APIKeysRotateRoute = "/api/v1/admin/api_keys/rotate"
+	APIKeysRevokeRoute = "/api/v1/admin/api_keys/revoke"
 )
 
 type DeployReq struct {
@@ -26,6 +30,13 @@ type DeployReq struct {
 	Bundle string `json:"bundle"`
 }
 
+type APIKeyResp struct {
+	auth.APIKey
+
+	// Key is only returned when a key is created or rotated
+	Key string `json:"key,omitempty"`
+}
+
 const (
 	errAuthFailed = "auth failed"
 	errNotFound   = "api not found"
@@ -100,6 +111,36 @@ func (c *Client) Rollback() (*Resp, error) {
 	return &Resp{Msg: string(res.Body())}, nil
 }
 
+// CreateAPIKey creates a new api key for a user
+func (c *Client) CreateAPIKey(k auth.APIKey) (*APIKeyResp, error) {
+	return c.apiKey(APIKeysRoute, k, "create api key failed: %w")
+}
+
+// RotateAPIKey replaces an api key with a new one
+func (c *Client) RotateAPIKey(id string) (*APIKeyResp, error) {
+	return c.apiKey(APIKeysRotateRoute, auth.APIKey{ID: id}, "rotate api key failed: %w")
+}
+
+// RevokeAPIKey revokes an api key
+func (c *Client) RevokeAPIKey(id string) (*APIKeyResp, error) {
+	return c.apiKey(APIKeysRevokeRoute, auth.APIKey{ID: id}, "revoke api key failed: %w")
+}
+
+// apiKey calls an api keys admin endpoint
+func (c *Client) apiKey(route string, k auth.APIKey, errMsg string) (*APIKeyResp, error) {
+	var res APIKeyResp
+
+	_, err := c.R().
+		SetBody(k).
+		SetResult(&res).
+		Post(route)
+	if err != nil {
+		return nil, fmt.Errorf(errMsg, err)
+	}
+
+	return &res, nil
+}
+
 // buildBundle creates a zip archive of the configuration directory
 func buildBundle(confPath string) (string, error) {
 	buf := bytes.Buffer{}
@@ -79,12 +79,27 @@ type Serv struct {
 	// Port to run the service on
 	Port string `jsonschema:"title=Port"`
 
+	// Serves the API over TLS and verifies client certificates
+	TLS TLS `mapstructure:"tls" jsonschema:"title=TLS"`
+
 	// Enables HTTP compression
 	HTTPGZip bool `mapstructure:"http_compress" jsonschema:"title=Enable Compression,default=true"`
 
 	// Sets the API rate limits
 	RateLimiter RateLimiter `mapstructure:"rate_limiter" jsonschema:"title=Set API Rate Limiting"`
 
+	// Sets the limits for batched requests (a JSON array of operations)
+	Batch Batch `mapstructure:"batch" jsonschema:"title=Batched Requests"`
+
+	// Sets the limits and timeouts for websocket connections
+	WS WS `mapstructure:"websocket" jsonschema:"title=Websockets"`
+
+	// Maps REST routes with path parameters to named queries
+	Routes []Route `jsonschema:"title=REST Routes"`
+
+	// Serves the named queries as a gRPC and Connect service
+	GRPC GRPC `mapstructure:"grpc" jsonschema:"title=gRPC and Connect Service"`
+
 	// Enables the Server-Timing HTTP header
 	ServerTiming bool `mapstructure:"server_timing" jsonschema:"title=Server Timing HTTP Header,default=true"`
 
@@ -121,6 +136,9 @@ type Serv struct {
 	// Sets the default authentication used by the service
 	Auth Auth `jsonschema:"title=Authentication"`
 
+	// Authorization policies written in CEL that are checked for every operation
+	Policies []Policy `jsonschema:"title=Authorization Policies"`
+
 	// Database configuration
 	DB Database `mapstructure:"database" jsonschema:"title=Database"`
 }
@@ -178,6 +196,134 @@ type RateLimiter struct {
 
 	// The header that contains the client ip
 	IPHeader string `mapstructure:"ip_header" jsonschema:"title=IP From HTTP Header,example=X-Forwarded-For"`
+
+	// Key used to identify a client, unauthenticated clients and requests
+	// without a verified api key are identified by their ip
+	Key string `jsonschema:"title=Client Key,enum=ip,enum=user,enum=role,enum=api_key,default=ip"`
+
+	// Limits for specific roles and operations
+	Limits []RateLimit `jsonschema:"title=Limits"`
+}
+
+// RateLimit sets the limit for a role, an operation or both. A limit for a role
+// replaces the default limit for clients with that role while a limit for an
+// operation applies to the requests for the named query in addition to it.
+type RateLimit struct {
+	// Role the limit applies to
+	Role string `jsonschema:"title=Role"`
+
+	// Name of the query the limit applies to
+	Operation string `jsonschema:"title=Operation"`
+
+	// The number of events per second
+	Rate float64 `jsonschema:"title=Connection Rate"`
+
+	// Bucket a burst of at most 'bucket' number of events
+	Bucket int `jsonschema:"title=Bucket Size"`
+}
+
+// Policy is an authorization rule written in CEL. The policies are checked in
+// order, a matching deny policy rejects the operation, a matching allow policy
+// skips the policies that follow it and a matching filter policy adds its
+// filters to the tables of the operation.
+type Policy struct {
+	// Name of the policy
+	Name string `jsonschema:"title=Name"`
+
+	// CEL expression that decides if the policy matches the operation.
+	// Example: operation == "delete" && !("admin" in scopes)
+	When string `jsonschema:"title=Match Expression"`
+
+	// Action taken when the policy matches
+	Action string `jsonschema:"title=Action,enum=deny,enum=allow,enum=filter,default=deny"`
+
+	// Message returned when the operation is denied
+	Message string `jsonschema:"title=Deny Message"`
+
+	// Filters added to the tables of the operation, the key is the table name.
+	// Example: orders: ["{ org_id: { eq: $org_id } }"]
+	Filters map[string][]string `jsonschema:"title=Table Filters"`
+
+	// CEL expressions for the values of the variables used by the filters, the
+	// variables cannot be set by the client so use trusted values like the claims.
+	// Example: org_id: claims.org_id
+	Vars map[string]string `jsonschema:"title=Filter Variables"`
+}
+
+// Batch sets the limits for batched requests
+type Batch struct {
+	// Maximum number of operations allowed in a batch
+	MaxOperations int `mapstructure:"max_operations" jsonschema:"title=Maximum Operations,default=10"`
+
+	// Number of operations in a batch executed at the same time
+	Concurrency int `jsonschema:"title=Concurrency,default=4"`
+}
+
+// Route maps a HTTP method and path to a named query
+type Route struct {
+	// HTTP method of the route
+	Method string `jsonschema:"title=HTTP Method,enum=GET,enum=POST,enum=PUT,enum=PATCH,enum=DELETE"`
+
+	// Path of the route, path parameters are set using braces. Example /users/{id}
+	Path string `jsonschema:"title=Path"`
+
+	// Name of the query from the allow list to execute
+	Query string `jsonschema:"title=Query Name"`
+
+	// Query string parameters bound to variables (parameter: variable)
+	Params map[string]string `jsonschema:"title=Query String Parameters"`
+
+	// HTTP headers bound to variables (header: variable)
+	Headers map[string]string `jsonschema:"title=HTTP Headers"`
+
+	// Variable the request body is bound to. By default the fields
+	// of a JSON object body are used as the variables
+	Body string `jsonschema:"title=Body Variable"`
+}
+
+// GRPC configures the gRPC and Connect service generated from the named queries
+type GRPC struct {
+	// Enables the service
+	Enable bool `jsonschema:"title=Enable,default=false"`
+
+	// Protobuf package of the service
+	Package string `jsonschema:"title=Package,default=graphjin.v1"`
+
+	// Name of the service
+	Service string `jsonschema:"title=Service Name,default=GraphJin"`
+
+	// Role the queries are compiled for when generating the service
+	Role string `jsonschema:"title=Role,default=user"`
+}
+
+// TLS sets the certificate of the service and how client certificates
+// are verified, relative paths are relative to the config path
+type TLS struct {
+	// PEM encoded certificate of the service
+	CertFile string `mapstructure:"cert_file" jsonschema:"title=Certificate File"`
+
+	// PEM encoded private key of the service
+	KeyFile string `mapstructure:"key_file" jsonschema:"title=Private Key File"`
+
+	// PEM encoded CA bundle used to verify client certificates
+	ClientCAFile string `mapstructure:"client_ca_file" jsonschema:"title=Client CA Bundle File"`
+
+	// ClientAuth can be one of request or require. With request client
+	// certificates are verified when sent and with require a verified
+	// certificate is needed to connect
+	ClientAuth string `mapstructure:"client_auth" jsonschema:"title=Client Certificates,enum=request,enum=require,default=request"`
+}
+
+// WS sets the limits and timeouts for websocket connections
+type WS struct {
+	// Maximum size of a message in bytes
+	ReadLimit int64 `mapstructure:"read_limit" jsonschema:"title=Maximum Message Size,default=65536"`
+
+	// Time to wait for the client to initialize the connection
+	InitTimeout time.Duration `mapstructure:"init_timeout" jsonschema:"title=Connection Init Timeout,default=3s"`
+
+	// Interval between keepalive pings sent to the client
+	PingInterval time.Duration `mapstructure:"ping_interval" jsonschema:"title=Ping Interval,default=15s"`
 }
 
 // Telemetry struct contains OpenCensus metrics and tracing related config
@@ -463,9 +609,19 @@ func (c *Config) SetName(name string) {
 	c.name = name
 }
 
+// OpenAPIRoutes returns the routes to include in the OpenAPI document
+func (c *Config) OpenAPIRoutes() []core.OpenAPIRoute {
+	routes := make([]core.OpenAPIRoute, 0, len(c.Routes))
+	for _, v := range c.Routes {
+		routes = append(routes, core.OpenAPIRoute(v))
+	}
+	return routes
+}
+
 // rateLimiterEnable returns true if the rate limiter is enabled
 func (c *Config) rateLimiterEnable() bool {
-	return c.RateLimiter.Rate > 0 && c.RateLimiter.Bucket > 0
+	return (c.RateLimiter.Rate > 0 && c.RateLimiter.Bucket > 0) ||
+		len(c.RateLimiter.Limits) != 0
 }
 
 // GetConfigName returns the name of the configuration
@@ -0,0 +1,334 @@
+package serv
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dosco/graphjin/core/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	exportNDJSON = "ndjson"
+	exportCSV    = "csv"
+
+	// the response is flushed after these many rows or this interval so
+	// that clients and proxies see the progress of a long export
+	exportFlushRows     = 500
+	exportFlushInterval = time.Second
+)
+
+// exportWriter streams the rows of an export to the response, the status
+// and headers are only written once the first columns are received so that
+// errors before that can still be returned as json
+type exportWriter interface {
+	core.Exporter
+	started() bool
+	flush() error
+}
+
+// apiV1Export returns a handler that streams the rows of a named query
+// as newline delimited json or csv
+func (s1 *HttpService) apiV1Export(ns *string) http.Handler {
+	dtrace := otel.GetTextMapPropagator()
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		var err error
+
+		start := time.Now()
+		s := s1.Load().(*graphjinService)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		ctx, opts := newDTrace(dtrace, r)
+		ctx, span := s.spanStart(ctx, "Export Request", opts...)
+		defer span.End()
+
+		queryName := r.URL.Path[strings.LastIndexByte(r.URL.Path, '/')+1:]
+		if queryName == "" {
+			err := errors.New("no query name defined")
+			spanError(span, err)
+			w.WriteHeader(http.StatusBadRequest)
+			renderErr(w, err)
+			return
+		}
+
+		if !s.rateLimitOp(w, r, queryName) {
+			return
+		}
+
+		var vars json.RawMessage
+
+		switch r.Method {
+		case "POST":
+			vars, err = parseBody(r)
+
+		case "GET":
+			vars = json.RawMessage(r.URL.Query().Get("variables"))
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			renderErr(w, errors.New("method not allowed: "+r.Method))
+			return
+		}
+
+		if err != nil {
+			spanError(span, err)
+			w.WriteHeader(http.StatusBadRequest)
+			renderErr(w, err)
+			return
+		}
+
+		var ew exportWriter
+		if exportFormat(r) == exportCSV {
+			ew = &csvExporter{exportStream: exportStream{w: w}}
+		} else {
+			ew = &ndjsonExporter{exportStream: exportStream{w: w}}
+		}
+
+		rc := s.newRequestConfig(r, gqlReq{}, ns)
+		res, err := s.gj.ExportByName(ctx, queryName, vars, &rc, ew)
+
+		if err == nil {
+			err = ew.flush()
+		}
+
+		if span.IsRecording() {
+			span.SetAttributes(
+				attribute.String("http.path", r.RequestURI),
+				attribute.String("http.method", r.Method),
+				attribute.String("query.name", queryName))
+		}
+
+		if err != nil {
+			spanError(span, err)
+		}
+
+		if res != nil && s.hook != nil {
+			s.hook(res)
+		}
+
+		if res != nil && s.logLevel >= logLevelInfo {
+			s.reqLog(ctx, res, rc, time.Since(start).Milliseconds(), err)
+		}
+
+		// the response has already begun so the error can only be logged
+		if ew.started() {
+			return
+		}
+
+		if err == nil {
+			err = errors.New("export failed")
+		}
+
+		w.WriteHeader(restStatus(r, res, err))
+		if res != nil && len(res.Validation) != 0 {
+			_ = json.NewEncoder(w).Encode(res)
+			return
+		}
+		renderErr(w, err)
+	}
+	return http.HandlerFunc(h)
+}
+
+// exportFormat returns the format requested using the format query
+// parameter or the accept header, the default is ndjson
+func exportFormat(r *http.Request) string {
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case exportCSV:
+		return exportCSV
+	case exportNDJSON:
+		return exportNDJSON
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		return exportCSV
+	}
+	return exportNDJSON
+}
+
+// exportStream is the response the rows of an export are streamed to
+type exportStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	rows    int
+	flushed time.Time
+	begun   bool
+}
+
+// begin writes the status and the headers of the response
+func (es *exportStream) begin(contentType string) {
+	es.w.Header().Set("Content-Type", contentType)
+
+	es.rc = http.NewResponseController(es.w)
+
+	// exports outlive the server write timeout
+	es.rc.SetWriteDeadline(time.Time{}) //nolint:errcheck
+
+	es.w.WriteHeader(http.StatusOK)
+	es.flushed = time.Now()
+	es.begun = true
+}
+
+// next counts a row and returns true if the response is due to be flushed
+func (es *exportStream) next() bool {
+	es.rows++
+	return es.rows%exportFlushRows == 0 || time.Since(es.flushed) >= exportFlushInterval
+}
+
+// flushResponse sends the buffered response to the client
+func (es *exportStream) flushResponse() {
+	es.rc.Flush() //nolint:errcheck
+	es.flushed = time.Now()
+}
+
+func (es *exportStream) started() bool {
+	return es.begun
+}
+
+// ndjsonExporter writes each row as a line of json
+type ndjsonExporter struct {
+	exportStream
+}
+
+func (e *ndjsonExporter) Columns(cols []string) error {
+	e.begin("application/x-ndjson")
+	return nil
+}
+
+func (e *ndjsonExporter) Row(row json.RawMessage) error {
+	if _, err := e.w.Write(row); err != nil {
+		return err
+	}
+	if _, err := e.w.Write([]byte{'\n'}); err != nil {
+		return err
+	}
+	if e.next() {
+		e.flushResponse()
+	}
+	return nil
+}
+
+func (e *ndjsonExporter) flush() error {
+	if e.begun {
+		e.flushResponse()
+	}
+	return nil
+}
+
+// csvExporter writes a header with the column names followed by a
+// record for each row, nested objects are flattened into the columns
+// and lists are written as json
+type csvExporter struct {
+	exportStream
+	cw   *csv.Writer
+	cols []string
+	rec  []string
+}
+
+func (e *csvExporter) Columns(cols []string) error {
+	e.begin("text/csv")
+
+	e.cols = cols
+	e.rec = make([]string, len(cols))
+	e.cw = csv.NewWriter(e.w)
+	return e.cw.Write(cols)
+}
+
+func (e *csvExporter) Row(row json.RawMessage) error {
+	// objects are cached by their path so that each of
+	// them is only decoded once for a row
+	objs := map[string]map[string]json.RawMessage{}
+
+	for i, col := range e.cols {
+		v, err := csvLookup(row, col, objs)
+		if err != nil {
+			return err
+		}
+		e.rec[i] = csvValue(v)
+	}
+	if err := e.cw.Write(e.rec); err != nil {
+		return err
+	}
+	if e.next() {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *csvExporter) flush() error {
+	if e.cw == nil {
+		return nil
+	}
+	e.cw.Flush()
+	if err := e.cw.Error(); err != nil {
+		return err
+	}
+	e.flushResponse()
+	return nil
+}
+
+// csvLookup returns the value of a flattened column (eg. user.email) from a row
+func csvLookup(row json.RawMessage, col string, objs map[string]map[string]json.RawMessage) (json.RawMessage, error) {
+	var path string
+	v := row
+
+	for {
+		n := strings.IndexByte(col[len(path):], '.')
+		if n == -1 {
+			break
+		}
+		key := col[len(path) : len(path)+n]
+
+		obj, err := csvObject(v, path, objs)
+		if err != nil || obj == nil {
+			return nil, err
+		}
+		path += key + "."
+		v = obj[key]
+	}
+
+	obj, err := csvObject(v, path, objs)
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	return obj[col[len(path):]], nil
+}
+
+// csvObject decodes the json object at a path, null values return a nil object
+func csvObject(v json.RawMessage, path string, objs map[string]map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	if obj, ok := objs[path]; ok {
+		return obj, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if len(v) != 0 {
+		if err := json.Unmarshal(v, &obj); err != nil {
+			return nil, err
+		}
+	}
+	objs[path] = obj
+	return obj, nil
+}
+
+// csvValue returns the text of a json value, strings are unquoted, nulls
+// are empty and everything else is written as is
+func csvValue(v json.RawMessage) string {
+	switch {
+	case len(v) == 0 || string(v) == "null":
+		return ""
+
+	case v[0] == '"':
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			return s
+		}
+	}
+	return string(v)
+}
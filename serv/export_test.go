@@ -0,0 +1,34 @@
+package serv
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	w := httptest.NewRecorder()
+	e := &csvExporter{exportStream: exportStream{w: w}}
+
+	assert.NoError(t, e.Columns([]string{"id", "user.email"}))
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+
+	// the rows are flushed to the client while the export is running
+	for i := 0; i < exportFlushRows; i++ {
+		assert.False(t, w.Flushed)
+		assert.NoError(t, e.Row(json.RawMessage(`{"id": 1, "user": {"email": "a@b.c"}}`)))
+	}
+	assert.True(t, w.Flushed)
+	assert.Contains(t, w.Body.String(), "id,user.email\n1,a@b.c\n")
+
+	w = httptest.NewRecorder()
+	n := &ndjsonExporter{exportStream: exportStream{w: w}}
+
+	assert.NoError(t, n.Columns([]string{"id"}))
+	assert.NoError(t, n.Row(json.RawMessage(`{"id":1}`)))
+	assert.NoError(t, n.flush())
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "{\"id\":1}\n", w.Body.String())
+}
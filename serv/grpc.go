@@ -0,0 +1,301 @@
+package serv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dosco/graphjin/core/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// grpcMethod is a rpc method and the named query it executes
+type grpcMethod struct {
+	query string
+	input protoreflect.MessageDescriptor
+}
+
+// grpcReqKey is the context key of the http request of a gRPC call
+type grpcReqKey struct{}
+
+// connectCodes maps the gRPC status codes to the Connect protocol
+// error codes and HTTP status codes
+var connectCodes = map[codes.Code]struct {
+	name   string
+	status int
+}{
+	codes.InvalidArgument:   {"invalid_argument", http.StatusBadRequest},
+	codes.NotFound:          {"not_found", http.StatusNotFound},
+	codes.Unauthenticated:   {"unauthenticated", http.StatusUnauthorized},
+	codes.ResourceExhausted: {"resource_exhausted", http.StatusTooManyRequests},
+	codes.Unimplemented:     {"unimplemented", http.StatusNotImplemented},
+	codes.Internal:          {"internal", http.StatusInternalServerError},
+}
+
+// apiV1GRPC returns a handler that serves the rpc methods generated from
+// the named queries using both the gRPC and the Connect protocol
+func (s1 *HttpService) apiV1GRPC(ns *string) (route string, h http.Handler, err error) {
+	s := s1.Load().(*graphjinService)
+
+	fdp, pml, err := newProtoFile(context.Background(), s.gj, s.conf.GRPC)
+	if err != nil {
+		return
+	}
+
+	fd, err := newProtoDesc(fdp)
+	if err != nil {
+		return
+	}
+	sd := fd.Services().Get(0)
+
+	methods := make(map[string]grpcMethod, len(pml))
+	desc := grpc.ServiceDesc{
+		ServiceName: string(sd.FullName()),
+		HandlerType: (*interface{})(nil),
+		Metadata:    protoFileName,
+	}
+
+	for _, pm := range pml {
+		m := grpcMethod{
+			query: pm.query,
+			input: sd.Methods().ByName(protoreflect.Name(pm.name)).Input(),
+		}
+		methods[pm.name] = m
+
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: pm.name,
+			Handler: func(_ interface{}, c context.Context,
+				dec func(interface{}) error, _ grpc.UnaryServerInterceptor,
+			) (interface{}, error) {
+				in := dynamicpb.NewMessage(m.input)
+				if err := dec(in); err != nil {
+					return nil, status.Error(codes.InvalidArgument, err.Error())
+				}
+				r, ok := c.Value(grpcReqKey{}).(*http.Request)
+				if !ok {
+					return nil, status.Error(codes.Internal, "http request not found")
+				}
+				return s1.grpcCall(r.WithContext(c), ns, m, in)
+			},
+		})
+	}
+
+	gs := grpc.NewServer()
+	gs.RegisterService(&desc, nil)
+
+	route = "/" + desc.ServiceName + "/*"
+
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			// the request is passed on to the method handlers for the
+			// header variables and the rate limits of the client
+			gs.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), grpcReqKey{}, r)))
+			return
+		}
+		s1.connectHandler(w, r, ns, methods)
+	}
+	return route, http.HandlerFunc(hf), nil
+}
+
+// connectHandler handles unary rpc calls made using the Connect protocol
+func (s1 *HttpService) connectHandler(w http.ResponseWriter,
+	r *http.Request,
+	ns *string,
+	methods map[string]grpcMethod,
+) {
+	name := r.URL.Path[strings.LastIndexByte(r.URL.Path, '/')+1:]
+
+	m, ok := methods[name]
+	if !ok {
+		connectError(w, status.Error(codes.Unimplemented, "unknown method: "+name))
+		return
+	}
+
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ct := r.Header.Get("Content-Type")
+	if ct != "application/json" && ct != "application/proto" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	b, err := io.ReadAll(io.LimitReader(r.Body, maxReadBytes))
+	if err != nil {
+		connectError(w, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	in := dynamicpb.NewMessage(m.input)
+	if ct == "application/json" {
+		err = protojson.Unmarshal(b, in)
+	} else {
+		err = proto.Unmarshal(b, in)
+	}
+	if err != nil {
+		connectError(w, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	out, err := s1.grpcCall(r, ns, m, in)
+	if err != nil {
+		connectError(w, err)
+		return
+	}
+
+	if ct == "application/json" {
+		b, err = protojson.Marshal(out)
+	} else {
+		b, err = proto.Marshal(out)
+	}
+	if err != nil {
+		connectError(w, status.Error(codes.Internal, err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", ct)
+	_, _ = w.Write(b)
+}
+
+// connectError writes an error using the Connect protocol
+func connectError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+
+	cc, ok := connectCodes[st.Code()]
+	if !ok {
+		cc = connectCodes[codes.Internal]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(cc.status)
+
+	_ = json.NewEncoder(w).Encode(struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}{cc.name, st.Message()})
+}
+
+// grpcCall executes the named query of the method with the variables
+// from the request message and returns the data as a struct
+func (s1 *HttpService) grpcCall(r *http.Request,
+	ns *string,
+	m grpcMethod,
+	in protoreflect.Message,
+) (*structpb.Struct, error) {
+	start := time.Now()
+	s := s1.Load().(*graphjinService)
+
+	if err := s.allowOp(r, m.query); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	c, span := s.spanStart(r.Context(), "gRPC Request")
+	defer span.End()
+
+	vars, err := protoVars(in)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	rc := s.newRequestConfig(r, gqlReq{}, ns)
+
+	res, err := s.gj.GraphQLByName(c, m.query, vars, &rc)
+
+	if res != nil && s.hook != nil {
+		s.hook(res)
+	}
+
+	if res != nil && s.logLevel >= logLevelInfo {
+		s.reqLog(c, res, rc, time.Since(start).Milliseconds(), err)
+	}
+
+	if err != nil {
+		spanError(span, err)
+		return nil, status.Error(grpcCode(res, err), err.Error())
+	}
+
+	out := &structpb.Struct{}
+	if len(res.Data) != 0 && string(res.Data) != "null" {
+		if err := protojson.Unmarshal(res.Data, out); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	return out, nil
+}
+
+// grpcCode returns the gRPC status code for the error of a query
+func grpcCode(res *core.Result, err error) codes.Code {
+	switch {
+	case res != nil && len(res.Validation) != 0:
+		return codes.InvalidArgument
+
+	case errors.Is(err, core.ErrUnknownQuery):
+		return codes.NotFound
+
+	case errors.Is(err, core.ErrInvalidVariable):
+		return codes.InvalidArgument
+
+	case errors.Is(err, errUnauthorized):
+		return codes.Unauthenticated
+	}
+	return codes.Internal
+}
+
+// protoVars returns the fields set in the message as json variables
+func protoVars(m protoreflect.Message) (json.RawMessage, error) {
+	vm := make(map[string]json.RawMessage)
+
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		var b []byte
+
+		if fd.IsList() {
+			l := v.List()
+			vl := make([]json.RawMessage, l.Len())
+
+			for i := range vl {
+				if vl[i], err = protoValueJSON(fd, l.Get(i)); err != nil {
+					return false
+				}
+			}
+			b, err = json.Marshal(vl)
+		} else {
+			b, err = protoValueJSON(fd, v)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("%s: %w", fd.Name(), err)
+			return false
+		}
+		vm[string(fd.Name())] = b
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(vm)
+}
+
+// protoValueJSON returns the json of a single value of a field
+func protoValueJSON(fd protoreflect.FieldDescriptor, v protoreflect.Value) ([]byte, error) {
+	if fd.Kind() == protoreflect.MessageKind {
+		return protojson.Marshal(v.Message().Interface())
+	}
+	return json.Marshal(v.Interface())
+}
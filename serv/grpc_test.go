@@ -0,0 +1,43 @@
+package serv
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestGRPC(t *testing.T) {
+	conf := &Config{}
+	conf.RateLimiter.Limits = []RateLimit{{Operation: "getUsers", Rate: 0.001, Bucket: 1}}
+
+	s1 := &HttpService{}
+	s := &graphjinService{conf: conf, zlog: zap.NewNop(), rlStore: newMemoryRateLimitStore()}
+	s1.Store(s)
+
+	methods := map[string]grpcMethod{
+		"GetUsers": {query: "getUsers", input: (&structpb.Struct{}).ProtoReflect().Descriptor()},
+	}
+
+	call := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/graphjin.v1.GraphJin/GetUsers", strings.NewReader("{}"))
+		r.Header.Set("Content-Type", "application/json")
+		r.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		s1.connectHandler(w, r, nil, methods)
+		return w
+	}
+
+	// the limit of the operation is used up by an earlier request of the client
+	r := httptest.NewRequest("POST", "/api/v1/graphql", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	assert.NoError(t, s.allowOp(r, "getUsers"))
+
+	w := call()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"resource_exhausted"`)
+}
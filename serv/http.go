@@ -1,6 +1,7 @@
 package serv
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"encoding/json"
@@ -37,6 +38,10 @@ var errUnauthorized = errors.New("not authorized")
 
 type extensions struct {
 	Persisted apqExt `json:"persistedQuery"`
+
+	// OperationID is used by the single connection mode
+	// of the GraphQL over SSE protocol
+	OperationID string `json:"operationId"`
 }
 
 type apqExt struct {
@@ -64,6 +69,12 @@ func apiV1Handler(s1 *HttpService, ns *string, h http.Handler, ah auth.HandlerFu
 		zlog = s.zlog
 	}
 
+	// the limits can depend on the user so the rate
+	// limiter is called after the authentication
+	if s.conf.rateLimiterEnable() {
+		h = rateLimiter(s1, h)
+	}
+
 	if ah != nil {
 		authOpt := auth.Options{AuthFailBlock: s.conf.Serv.AuthFailBlock}
 		useAuth, err := auth.NewAuth(s.conf.Auth, zlog, authOpt, ah)
@@ -76,6 +87,7 @@ func apiV1Handler(s1 *HttpService, ns *string, h http.Handler, ah auth.HandlerFu
 	if len(s.conf.AllowedOrigins) != 0 {
 		allowedHeaders := []string{
 			"Origin", "Accept", "Content-Type", "X-Requested-With", "Authorization",
+			sseTokenHeader, "Last-Event-ID", batchTxHeader,
 		}
 
 		if len(s.conf.AllowedHeaders) != 0 {
@@ -85,6 +97,7 @@ func apiV1Handler(s1 *HttpService, ns *string, h http.Handler, ah auth.HandlerFu
 		c := cors.New(cors.Options{
 			AllowedOrigins:   s.conf.AllowedOrigins,
 			AllowedHeaders:   allowedHeaders,
+			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "HEAD"},
 			AllowCredentials: true,
 			Debug:            s.conf.DebugCORS,
 		})
@@ -93,10 +106,6 @@ func apiV1Handler(s1 *HttpService, ns *string, h http.Handler, ah auth.HandlerFu
 
 	h = etags.Handler(h, false)
 
-	if s.conf.rateLimiterEnable() {
-		h = rateLimiter(s1, h)
-	}
-
 	if s.conf.HTTPGZip {
 		gz, err := gzhttp.NewWrapper(gzhttp.CompressionLevel(6))
 		if err != nil {
@@ -121,77 +130,70 @@ func (s1 *HttpService) apiV1GraphQL(ns *string, ah auth.HandlerFunc) http.Handle
 		w.Header().Set("Content-Type", "application/json")
 
 		if websocket.IsWebSocketUpgrade(r) {
-			s.apiV1Ws(w, r, ah)
+			s.apiV1Ws(w, r, ns, ah)
 			return
 		}
 
-		var req gqlReq
+		if isSSE(r) {
+			s.apiV1SSE(w, r, ns)
+			return
+		}
 
 		ctx, opts := newDTrace(dtrace, r)
 		ctx, span := s.spanStart(ctx, "GraphQL Request", opts...)
 		defer span.End()
 
-		switch r.Method {
-		case "POST":
-			var b []byte
-			b, err = io.ReadAll(io.LimitReader(r.Body, maxReadBytes))
-			if err == nil {
-				defer r.Body.Close()
-				err = json.Unmarshal(b, &req)
-			}
-
-		case "GET":
-			q := r.URL.Query()
-			req.Query = q.Get("query")
-			req.OpName = q.Get("operationName")
-			req.Vars = json.RawMessage(q.Get("variables"))
-
-			if ext := q.Get("extensions"); ext != "" {
-				err = json.Unmarshal([]byte(ext), &req.Ext)
-			}
-		}
-
+		req, batch, err := parseGQLBatch(r)
 		if err != nil {
 			spanError(span, err)
 			renderErr(w, err)
 			return
 		}
 
-		var rc core.RequestConfig
-
-		if req.apqEnabled() {
-			rc.APQKey = (req.OpName + req.Ext.Persisted.Sha256Hash)
+		if batch != nil {
+			s.apiV1Batch(ctx, w, r, batch, ns)
+			return
 		}
 
-		if rc.Vars == nil && len(s.conf.Core.HeaderVars) != 0 {
-			rc.Vars = s.setHeaderVars(r)
+		if !s.rateLimitOp(w, r, s.opName(req)) {
+			return
 		}
 
-		if ns != nil {
-			rc.SetNamespace(*ns)
-		}
+		rc := s.newRequestConfig(r, req, ns)
 
 		if req.OpName == "subscription" {
-			err := errors.New("use websockets for subscriptions")
+			err := errors.New("use websockets or server-sent events for subscriptions")
 			spanError(span, err)
 			renderErr(w, err)
 			return
 		}
 
-		res, err := s.gj.GraphQL(ctx, req.Query, req.Vars, &rc)
+		var res *core.Result
+		var next <-chan *core.Result
+
+		if acceptsMultipart(r) {
+			res, next, err = s.gj.GraphQLIncremental(ctx, req.Query, req.Vars, &rc)
+		} else {
+			res, err = s.gj.GraphQL(ctx, req.Query, req.Vars, &rc)
+		}
+
 		if res == nil && err != nil {
 			renderErr(w, err)
 			return
 		}
 
-		s.responseHandler(
-			ctx,
-			w,
-			r,
-			start,
-			rc,
-			res,
-			err)
+		if next != nil {
+			s.multipartHandler(ctx, w, r, start, rc, res, next)
+		} else {
+			s.responseHandler(
+				ctx,
+				w,
+				r,
+				start,
+				rc,
+				res,
+				err)
+		}
 
 		if span.IsRecording() {
 			span.SetAttributes(
@@ -221,7 +223,7 @@ func (s1 *HttpService) apiV1Rest(ns *string, ah auth.HandlerFunc) http.Handler {
 		w.Header().Set("Content-Type", "application/json")
 
 		if websocket.IsWebSocketUpgrade(r) {
-			s.apiV1Ws(w, r, ah)
+			s.apiV1Ws(w, r, ns, ah)
 			return
 		}
 
@@ -244,6 +246,10 @@ func (s1 *HttpService) apiV1Rest(ns *string, ah auth.HandlerFunc) http.Handler {
 			queryName = queryName[:n]
 		}
 
+		if !s.rateLimitOp(w, r, queryName) {
+			return
+		}
+
 		switch r.Method {
 		case "POST":
 			vars, err = parseBody(r)
@@ -324,7 +330,7 @@ func (s *graphjinService) responseHandler(ct context.Context,
 	rt := time.Since(start).Milliseconds()
 
 	if s.logLevel >= logLevelInfo {
-		s.reqLog(res, rc, rt, err)
+		s.reqLog(ct, res, rc, rt, err)
 	}
 
 	if s.conf.ServerTiming {
@@ -335,7 +341,12 @@ func (s *graphjinService) responseHandler(ct context.Context,
 }
 
 // reqLog logs the request details
-func (s *graphjinService) reqLog(res *core.Result, rc core.RequestConfig, resTimeMs int64, err error) {
+func (s *graphjinService) reqLog(c context.Context,
+	res *core.Result,
+	rc core.RequestConfig,
+	resTimeMs int64,
+	err error,
+) {
 	var fields []zapcore.Field
 	var sql string
 
@@ -353,6 +364,10 @@ func (s *graphjinService) reqLog(res *core.Result, rc core.RequestConfig, resTim
 		fields = append(fields, zap.String("namespace", ns))
 	}
 
+	if m := auth.Method(c); m != "" {
+		fields = append(fields, zap.String("auth", m))
+	}
+
 	if res.Vars != nil && s.conf.Core.LogVars {
 		var vars map[string]interface{}
 		err := json.Unmarshal(res.Vars, &vars)
@@ -392,6 +407,77 @@ func (s *graphjinService) setHeaderVars(r *http.Request) map[string]interface{}
 	return vars
 }
 
+// newRequestConfig returns the request config for a graphql request
+func (s *graphjinService) newRequestConfig(r *http.Request, req gqlReq, ns *string) (rc core.RequestConfig) {
+	if req.apqEnabled() {
+		rc.APQKey = req.Ext.Persisted.Sha256Hash
+	}
+
+	if len(s.conf.Core.HeaderVars) != 0 {
+		rc.Vars = s.setHeaderVars(r)
+	}
+
+	if ns != nil {
+		rc.SetNamespace(*ns)
+	}
+	return
+}
+
+// parseGQLReq reads the graphql request from the body or the query string
+func parseGQLReq(r *http.Request) (req gqlReq, err error) {
+	var batch []gqlReq
+	if req, batch, err = parseGQLBatch(r); err == nil && batch != nil {
+		err = errors.New("batched requests are not supported here")
+	}
+	return
+}
+
+// parseGQLBatch is similar to parseGQLReq except that a POST body
+// can also be an array of requests
+func parseGQLBatch(r *http.Request) (req gqlReq, batch []gqlReq, err error) {
+	switch r.Method {
+	case "POST":
+		var b []byte
+		b, err = io.ReadAll(io.LimitReader(r.Body, maxReadBytes))
+		if err != nil {
+			return
+		}
+		defer r.Body.Close()
+
+		if b = bytes.TrimSpace(b); len(b) != 0 && b[0] == '[' {
+			batch = []gqlReq{}
+			err = json.Unmarshal(b, &batch)
+		} else {
+			err = json.Unmarshal(b, &req)
+		}
+
+	case "GET":
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OpName = q.Get("operationName")
+		req.Vars = json.RawMessage(q.Get("variables"))
+
+		if ext := q.Get("extensions"); ext != "" {
+			err = json.Unmarshal([]byte(ext), &req.Ext)
+		}
+	}
+	return
+}
+
+// opName returns the name of the operation from the query, for apq requests
+// the query saved under the apq key is used. The operation name sent by the
+// client is not used since it does not have to match the query.
+func (s *graphjinService) opName(req gqlReq) string {
+	if req.apqEnabled() {
+		h, err := s.gj.PersistedOperation(req.Ext.Persisted.Sha256Hash)
+		if err == nil && h.Name != "" {
+			return h.Name
+		}
+	}
+	h, _ := core.Operation(req.Query)
+	return h.Name
+}
+
 // apqEnabled checks if the APQ is enabled
 func (r gqlReq) apqEnabled() bool {
 	return r.Ext.Persisted.Sha256Hash != ""
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/dosco/graphjin/core/v3"
 )
@@ -40,6 +41,10 @@ func validateConf(s *graphjinService) {
 		s.log.Warn("unauthenticated requests will be blocked. no role 'anon' defined")
 		s.conf.AuthFailBlock = false
 	}
+
+	if _, ok := s.conf.Auth.FindType("mtls"); ok && s.conf.TLS.ClientCAFile == "" {
+		s.log.Warn("mtls auth needs tls.client_ca_file to be set to verify client certificates")
+	}
 }
 
 // initFS initializes the file system
@@ -66,12 +71,10 @@ func (s *graphjinService) initConfig() error {
 		c.Core.DBType = c.DB.Type
 	}
 
-	if c.HotDeploy {
-		if c.AdminSecretKey != "" {
-			s.asec = sha256.Sum256([]byte(s.conf.AdminSecretKey))
-		} else {
-			return fmt.Errorf("please set an admin_secret_key")
-		}
+	if c.AdminSecretKey != "" {
+		s.asec = sha256.Sum256([]byte(s.conf.AdminSecretKey))
+	} else if c.HotDeploy {
+		return fmt.Errorf("please set an admin_secret_key")
 	}
 
 	if c.Auth.Type == "" || c.Auth.Type == "none" {
@@ -96,6 +99,26 @@ func (s *graphjinService) initConfig() error {
 		s.conf.hostPort = defaultHP
 	}
 
+	if c.Batch.MaxOperations == 0 {
+		c.Batch.MaxOperations = 10
+	}
+
+	if c.Batch.Concurrency == 0 {
+		c.Batch.Concurrency = 4
+	}
+
+	if c.WS.ReadLimit == 0 {
+		c.WS.ReadLimit = 65536
+	}
+
+	if c.WS.InitTimeout == 0 {
+		c.WS.InitTimeout = 3 * time.Second
+	}
+
+	if c.WS.PingInterval == 0 {
+		c.WS.PingInterval = 15 * time.Second
+	}
+
 	c.Core.Production = c.Serv.Production
 	return nil
 }
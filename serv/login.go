@@ -0,0 +1,182 @@
+package serv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dosco/graphjin/auth/v3"
+	"go.uber.org/zap"
+)
+
+type loginReq struct {
+	Login string `json:"login"`
+	Code  string `json:"code"`
+}
+
+// LoginResp is the response of the login token endpoint
+type LoginResp struct {
+	Token     string `json:"token"`
+	TokenType string `json:"token_type"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// OptionSetCodeSender sets the sender used to deliver the login codes,
+// without it the codes are written to the log
+func OptionSetCodeSender(cs auth.CodeSender) Option {
+	return func(s *graphjinService) error {
+		s.codeSender = cs
+		return nil
+	}
+}
+
+// logCodeSender writes the login codes to the log, it's only for development
+type logCodeSender struct {
+	log *zap.SugaredLogger
+}
+
+func (ls logCodeSender) SendCode(_ context.Context, login, code string) error {
+	ls.log.Infof("login code for %s: %s", login, code)
+	return nil
+}
+
+// initLogin sets up the login store when the built-in login is enabled
+func (s *graphjinService) initLogin() (err error) {
+	ac, ok := s.conf.Auth.FindType("jwt")
+	if !ok || !ac.Login.Enable {
+		return
+	}
+
+	if ac.JWT.Secret == "" {
+		return fmt.Errorf("login: no jwt.secret defined")
+	}
+
+	if s.codeSender == nil {
+		if s.conf.Serv.Production {
+			return fmt.Errorf("login: no code sender set")
+		}
+		s.log.Warn("login: no code sender set, login codes will be written to the log")
+		s.codeSender = logCodeSender{log: s.log}
+	}
+
+	s.login, err = auth.NewLoginStore(s.db, s.conf.DBType, ac.Login)
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	return
+}
+
+// loginCodeHandler creates a login code and sends it to the user, unknown
+// logins get the same response so they cannot be discovered
+func loginCodeHandler(s1 *HttpService) http.Handler {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		var req loginReq
+
+		s := s1.Load().(*graphjinService)
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		de := json.NewDecoder(r.Body)
+		if err := de.Decode(&req); err != nil {
+			badReq(w, err.Error())
+			return
+		}
+
+		if req.Login == "" {
+			badReq(w, "login is a required field")
+			return
+		}
+
+		code, err := s.login.CreateCode(r.Context(), req.Login)
+		if err == auth.ErrLoginUnknown {
+			s.log.Infof("login: unknown login: %s", req.Login)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if err != nil {
+			intErr(w, fmt.Sprintf("login error: %s", err.Error()))
+			return
+		}
+
+		if err := s.codeSender.SendCode(r.Context(), req.Login, code); err != nil {
+			intErr(w, fmt.Sprintf("login error: %s", err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+
+	return http.HandlerFunc(h)
+}
+
+// loginTokenHandler verifies the login code and returns a jwt token,
+// the token is also set as a cookie when auth.cookie is defined
+func loginTokenHandler(s1 *HttpService) http.Handler {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		var req loginReq
+
+		s := s1.Load().(*graphjinService)
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		de := json.NewDecoder(r.Body)
+		if err := de.Decode(&req); err != nil {
+			badReq(w, err.Error())
+			return
+		}
+
+		if req.Login == "" || req.Code == "" {
+			badReq(w, "login and code are required fields")
+			return
+		}
+
+		userID, err := s.login.VerifyCode(r.Context(), req.Login, req.Code)
+		if err == auth.ErrLoginCode || err == auth.ErrLoginUnknown {
+			http.Error(w, auth.ErrLoginCode.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			intErr(w, fmt.Sprintf("login error: %s", err.Error()))
+			return
+		}
+
+		ac, _ := s.conf.Auth.FindType("jwt")
+
+		ttl := ac.Login.TokenTTL
+		if ttl == 0 {
+			ttl = time.Hour
+		}
+
+		tok, err := auth.IssueToken(ac.JWT, userID, ttl)
+		if err != nil {
+			intErr(w, fmt.Sprintf("login error: %s", err.Error()))
+			return
+		}
+
+		if ac.Cookie != "" {
+			http.SetCookie(w, &http.Cookie{
+				Name:     ac.Cookie,
+				Value:    tok,
+				Path:     "/",
+				Expires:  time.Now().Add(ttl),
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		res := LoginResp{Token: tok, TokenType: "Bearer", ExpiresIn: int(ttl.Seconds())}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	}
+
+	return http.HandlerFunc(h)
+}
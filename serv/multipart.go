@@ -0,0 +1,99 @@
+package serv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dosco/graphjin/core/v3"
+)
+
+const (
+	multipartContentType = `multipart/mixed; boundary="-"; deferSpec=20220824`
+	multipartBoundary    = "\r\n---"
+)
+
+// acceptsMultipart checks if the client can handle incremental
+// results delivered as a multipart response
+func acceptsMultipart(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "multipart/mixed")
+}
+
+// multipartHandler writes the initial and the incremental results
+// as parts of a multipart/mixed response
+func (s *graphjinService) multipartHandler(ct context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	start time.Time,
+	rc core.RequestConfig,
+	res *core.Result,
+	next <-chan *core.Result,
+) {
+	if s.hook != nil {
+		s.hook(res)
+	}
+
+	w.Header().Set("Content-Type", multipartContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	mw := http.NewResponseController(w)
+	mw.SetWriteDeadline(time.Time{}) //nolint:errcheck
+
+	var b bytes.Buffer
+	b.WriteString(multipartBoundary)
+
+	err := writePart(w, mw, &b, res)
+
+	for err == nil {
+		var v *core.Result
+		var ok bool
+
+		select {
+		case v, ok = <-next:
+		case <-ct.Done():
+			return
+		}
+		if !ok {
+			break
+		}
+		err = writePart(w, mw, &b, v)
+	}
+
+	if err == nil {
+		b.WriteString("--\r\n")
+		_, err = w.Write(b.Bytes())
+	}
+
+	if s.logLevel >= logLevelInfo {
+		s.reqLog(ct, res, rc, time.Since(start).Milliseconds(), err)
+	}
+}
+
+// writePart writes a result as a part and flushes it to the client
+func writePart(w http.ResponseWriter,
+	mw *http.ResponseController,
+	b *bytes.Buffer,
+	res *core.Result,
+) error {
+	b.WriteString("\r\nContent-Type: application/json; charset=utf-8\r\n\r\n")
+
+	if err := json.NewEncoder(b).Encode(res); err != nil {
+		return err
+	}
+	b.WriteString(multipartBoundary)
+
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return err
+	}
+	b.Reset()
+
+	if err := mw.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
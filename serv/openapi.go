@@ -0,0 +1,40 @@
+package serv
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dosco/graphjin/core/v3"
+)
+
+// apiV1OpenAPI returns a handler that serves the OpenAPI document
+// describing the REST endpoints
+func (s1 *HttpService) apiV1OpenAPI() http.Handler {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		s := s1.Load().(*graphjinService)
+
+		c, span := s.spanStart(r.Context(), "OpenAPI Request")
+		defer span.End()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		doc, err := s.gj.OpenAPI(c, s.openAPIConfig())
+		if err != nil {
+			spanError(span, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			renderErr(w, err)
+			return
+		}
+		_, _ = w.Write(doc)
+	}
+	return http.HandlerFunc(h)
+}
+
+// openAPIConfig returns the details of the OpenAPI document
+func (s *graphjinService) openAPIConfig() core.OpenAPIConfig {
+	return core.OpenAPIConfig{
+		Title:    s.conf.AppName,
+		BasePath: strings.TrimSuffix(routeREST, "/*"),
+		Routes:   s.conf.OpenAPIRoutes(),
+	}
+}
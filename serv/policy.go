@@ -0,0 +1,195 @@
+package serv
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dosco/graphjin/core/v3"
+	"github.com/google/cel-go/cel"
+)
+
+// celPolicy checks the operations against the policies written in CEL
+type celPolicy struct {
+	rules []celRule
+}
+
+type celRule struct {
+	Policy
+	name string
+	prg  cel.Program
+	vars map[string]cel.Program
+}
+
+// newCELPolicy compiles the policies, the CEL expressions have the following
+// variables: operation, name, role, user_id, scopes, claims, vars and tables.
+// Every table has a name, schema, operation and columns. The values of the
+// variables used by the filters are also CEL expressions.
+func newCELPolicy(policies []Policy) (*celPolicy, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("operation", cel.StringType),
+		cel.Variable("name", cel.StringType),
+		cel.Variable("role", cel.StringType),
+		cel.Variable("user_id", cel.DynType),
+		cel.Variable("scopes", cel.ListType(cel.StringType)),
+		cel.Variable("claims", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("vars", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("tables", cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &celPolicy{rules: make([]celRule, 0, len(policies))}
+
+	for i, p := range policies {
+		name := p.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		switch p.Action {
+		case "":
+			p.Action = "deny"
+		case "deny", "allow":
+		case "filter":
+			if len(p.Filters) == 0 {
+				return nil, fmt.Errorf("policy '%s': no filters defined", name)
+			}
+		default:
+			return nil, fmt.Errorf("policy '%s': invalid action: %s", name, p.Action)
+		}
+
+		if p.When == "" {
+			return nil, fmt.Errorf("policy '%s': no 'when' expression defined", name)
+		}
+
+		ast, iss := env.Compile(p.When)
+		if iss.Err() != nil {
+			return nil, fmt.Errorf("policy '%s': %w", name, iss.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf("policy '%s': 'when' expression must return a bool", name)
+		}
+
+		prg, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("policy '%s': %w", name, err)
+		}
+		r := celRule{Policy: p, name: name, prg: prg}
+
+		if len(p.Vars) != 0 && p.Action != "filter" {
+			return nil, fmt.Errorf("policy '%s': vars are only used by filter policies", name)
+		}
+
+		for k, v := range p.Vars {
+			ast, iss := env.Compile(v)
+			if iss.Err() != nil {
+				return nil, fmt.Errorf("policy '%s': var '%s': %w", name, k, iss.Err())
+			}
+			prg, err := env.Program(ast)
+			if err != nil {
+				return nil, fmt.Errorf("policy '%s': var '%s': %w", name, k, err)
+			}
+			if r.vars == nil {
+				r.vars = make(map[string]cel.Program, len(p.Vars))
+			}
+			r.vars[k] = prg
+		}
+		cp.rules = append(cp.rules, r)
+	}
+	return cp, nil
+}
+
+// Evaluate checks the operation against the policies in order
+func (cp *celPolicy) Evaluate(c context.Context, in core.PolicyInput) (res core.PolicyResult, err error) {
+	act := celActivation(in)
+
+	for _, r := range cp.rules {
+		out, _, err := r.prg.ContextEval(c, act)
+		if err != nil {
+			return res, fmt.Errorf("policy '%s': %w", r.name, err)
+		}
+
+		if v, ok := out.Value().(bool); !ok || !v {
+			continue
+		}
+
+		switch r.Action {
+		case "allow":
+			return res, nil
+
+		case "filter":
+			if res.Filters == nil {
+				res.Filters = make(map[string][]string)
+			}
+			for k, v := range r.Filters {
+				res.Filters[k] = append(res.Filters[k], v...)
+			}
+
+			for k, prg := range r.vars {
+				out, _, err := prg.ContextEval(c, act)
+				if err != nil {
+					return res, fmt.Errorf("policy '%s': var '%s': %w", r.name, k, err)
+				}
+				if res.Vars == nil {
+					res.Vars = make(map[string]interface{})
+				}
+				res.Vars[k] = out.Value()
+			}
+
+		default:
+			res.Deny = true
+			res.Reason = r.Message
+			if res.Reason == "" {
+				res.Reason = r.Name
+			}
+			res.Filters = nil
+			res.Vars = nil
+			return res, nil
+		}
+	}
+	return res, nil
+}
+
+// celActivation returns the variables used by the CEL expressions
+func celActivation(in core.PolicyInput) map[string]interface{} {
+	tables := make([]map[string]interface{}, len(in.Tables))
+	for i, t := range in.Tables {
+		cols := t.Columns
+		if cols == nil {
+			cols = []string{}
+		}
+		tables[i] = map[string]interface{}{
+			"name":      t.Name,
+			"schema":    t.Schema,
+			"operation": t.Type,
+			"columns":   cols,
+		}
+	}
+
+	scopes := in.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	claims := in.Claims
+	if claims == nil {
+		claims = map[string]interface{}{}
+	}
+
+	vars := in.Vars
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+
+	return map[string]interface{}{
+		"operation": in.Type,
+		"name":      in.Name,
+		"role":      in.Role,
+		"user_id":   in.UserID,
+		"scopes":    scopes,
+		"claims":    claims,
+		"vars":      vars,
+		"tables":    tables,
+	}
+}
@@ -0,0 +1,41 @@
+package serv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dosco/graphjin/core/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	cp, err := newCELPolicy([]Policy{{
+		Name:    "own_org",
+		When:    "has(claims.org_id)",
+		Action:  "filter",
+		Filters: map[string][]string{"orders": {"{ org_id: { eq: $org_id } }"}},
+		Vars:    map[string]string{"org_id": "claims.org_id"},
+	}})
+	assert.NoError(t, err)
+
+	// the value of the filter variable comes from the claims and
+	// not the variables sent by the client
+	res, err := cp.Evaluate(context.Background(), core.PolicyInput{
+		Claims: map[string]interface{}{"org_id": 5.0},
+		Vars:   map[string]interface{}{"org_id": 42.0},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"{ org_id: { eq: $org_id } }"}, res.Filters["orders"])
+	assert.Equal(t, 5.0, res.Vars["org_id"])
+
+	_, err = newCELPolicy([]Policy{{When: "true", Action: "deny", Vars: map[string]string{"a": "1"}}})
+	assert.Error(t, err)
+
+	_, err = newCELPolicy([]Policy{{
+		When:    "true",
+		Action:  "filter",
+		Filters: map[string][]string{"orders": {"{ id: { eq: $id } }"}},
+		Vars:    map[string]string{"id": "claims."},
+	}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,217 @@
+package serv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/dosco/graphjin/core/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	// registers google/protobuf/struct.proto
+	_ "google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	protoFileName   = "graphjin.proto"
+	protoStructFile = "google/protobuf/struct.proto"
+	protoStruct     = ".google.protobuf.Struct"
+	protoValue      = ".google.protobuf.Value"
+)
+
+// protoMethod maps a rpc method to a named query
+type protoMethod struct {
+	name  string
+	query string
+}
+
+// newProtoFile returns the descriptor of a service that has a rpc method for
+// each of the named queries, the request messages are derived from the
+// variables of the query and the responses are a google.protobuf.Struct
+func newProtoFile(c context.Context, gj *core.GraphJin, conf GRPC) (
+	fdp *descriptorpb.FileDescriptorProto, methods []protoMethod, err error,
+) {
+	if conf.Package == "" {
+		conf.Package = "graphjin.v1"
+	}
+	if conf.Service == "" {
+		conf.Service = "GraphJin"
+	}
+	if conf.Role == "" {
+		conf.Role = "user"
+	}
+	c = context.WithValue(c, core.UserRoleKey, conf.Role)
+
+	nql, err := gj.NamedQueries(c)
+	if err != nil {
+		return
+	}
+
+	fdp = &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(protoFileName),
+		Package:    proto.String(conf.Package),
+		Dependency: []string{protoStructFile},
+		Syntax:     proto.String("proto3"),
+	}
+
+	sdp := &descriptorpb.ServiceDescriptorProto{Name: proto.String(conf.Service)}
+	names := make(map[string]string, len(nql))
+
+	for _, nq := range nql {
+		m := protoMethod{name: protoName(nq.FullName()), query: nq.FullName()}
+		if m.name == "" {
+			continue
+		}
+
+		if v, ok := names[m.name]; ok {
+			err = fmt.Errorf("grpc: queries '%s' and '%s' have the same method name: %s",
+				v, m.query, m.name)
+			return
+		}
+		names[m.name] = m.query
+
+		msg := newProtoMessage(m.name+"Request", nq.Vars)
+		fdp.MessageType = append(fdp.MessageType, msg)
+
+		sdp.Method = append(sdp.Method, &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(m.name),
+			InputType:  proto.String("." + conf.Package + "." + msg.GetName()),
+			OutputType: proto.String(protoStruct),
+		})
+		methods = append(methods, m)
+	}
+
+	fdp.Service = []*descriptorpb.ServiceDescriptorProto{sdp}
+	return
+}
+
+// newProtoMessage returns a message with a field for each of the variables
+func newProtoMessage(name string, vars []core.NamedQueryVar) *descriptorpb.DescriptorProto {
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+
+	for i, v := range vars {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(v.Name),
+			JsonName: proto.String(v.Name),
+			Number:   proto.Int32(int32(i + 1)),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+
+		switch v.Type {
+		case "Int":
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()
+		case "Float":
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum()
+		case "Boolean":
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum()
+		case "JSON":
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			f.TypeName = proto.String(protoValue)
+		default:
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+		}
+
+		switch {
+		case v.Array:
+			f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+		// track presence so that unset variables are not sent as zero values
+		case f.GetType() != descriptorpb.FieldDescriptorProto_TYPE_MESSAGE:
+			f.Proto3Optional = proto.Bool(true)
+			f.OneofIndex = proto.Int32(int32(len(msg.OneofDecl)))
+			msg.OneofDecl = append(msg.OneofDecl, &descriptorpb.OneofDescriptorProto{
+				Name: proto.String("_" + v.Name),
+			})
+		}
+		msg.Field = append(msg.Field, f)
+	}
+	return msg
+}
+
+// protoName converts a query name into a rpc method name (eg. getUser to GetUser)
+func protoName(name string) string {
+	var sb strings.Builder
+	upper := true
+
+	for _, r := range name {
+		switch {
+		case r > unicode.MaxASCII:
+		case unicode.IsLetter(r), unicode.IsDigit(r) && sb.Len() != 0:
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			sb.WriteRune(r)
+		default:
+			upper = true
+		}
+	}
+	return sb.String()
+}
+
+// newProtoDesc returns the file descriptor built from the descriptor proto
+func newProtoDesc(fdp *descriptorpb.FileDescriptorProto) (protoreflect.FileDescriptor, error) {
+	return protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+}
+
+// ProtoFile returns the .proto file of the gRPC service generated from
+// the named queries in the allow list
+func ProtoFile(c context.Context, gj *core.GraphJin, conf GRPC) ([]byte, error) {
+	fdp, _, err := newProtoFile(c, gj, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	// validate the file
+	if _, err := newProtoDesc(fdp); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "syntax = \"proto3\";\n\npackage %s;\n\n", fdp.GetPackage())
+	fmt.Fprintf(&b, "import \"%s\";\n", protoStructFile)
+
+	for _, sdp := range fdp.Service {
+		fmt.Fprintf(&b, "\nservice %s {\n", sdp.GetName())
+		for _, m := range sdp.Method {
+			fmt.Fprintf(&b, "  rpc %s(%s) returns (%s);\n",
+				m.GetName(),
+				protoTypeName(fdp, m.GetInputType()),
+				protoTypeName(fdp, m.GetOutputType()))
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, msg := range fdp.MessageType {
+		fmt.Fprintf(&b, "\nmessage %s {\n", msg.GetName())
+		for _, f := range msg.Field {
+			var label string
+			switch {
+			case f.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED:
+				label = "repeated "
+			case f.GetProto3Optional():
+				label = "optional "
+			}
+
+			t := protoTypeName(fdp, f.GetTypeName())
+			if t == "" {
+				t = strings.ToLower(strings.TrimPrefix(f.GetType().String(), "TYPE_"))
+			}
+			fmt.Fprintf(&b, "  %s%s %s = %d;\n", label, t, f.GetName(), f.GetNumber())
+		}
+		b.WriteString("}\n")
+	}
+	return b.Bytes(), nil
+}
+
+// protoTypeName returns the name of a type relative to the package of the file
+func protoTypeName(fdp *descriptorpb.FileDescriptorProto, name string) string {
+	name = strings.TrimPrefix(name, ".")
+	return strings.TrimPrefix(name, fdp.GetPackage()+".")
+}
@@ -0,0 +1,317 @@
+package serv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dosco/graphjin/auth/v3"
+	"github.com/dosco/graphjin/core/v3"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RateLimitStore stores the state of the rate limits. The default store keeps
+// it in memory, use a shared store (eg. redis) with OptionSetRateLimitStore so
+// that the replicas of a service share the same limits.
+type RateLimitStore interface {
+	// Allow takes n events from the limit of the key
+	Allow(c context.Context, key string, limit RateLimit, n int) (RateLimitResult, error)
+}
+
+// RateLimitResult is the state of a limit after events are taken from it
+type RateLimitResult struct {
+	Allowed bool
+
+	// Number of events left
+	Remaining int
+
+	// Time until the limit is fully reset
+	Reset time.Duration
+
+	// Time to wait before retrying when the events are not allowed
+	RetryAfter time.Duration
+}
+
+// OptionSetRateLimitStore sets the store used by the rate limiter
+func OptionSetRateLimitStore(store RateLimitStore) Option {
+	return func(s *graphjinService) error {
+		s.rlStore = store
+		return nil
+	}
+}
+
+// rateLimiter is a middleware that limits the number of requests per client
+func rateLimiter(s1 *HttpService, h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		s := s1.Load().(*graphjinService)
+
+		if !s.rateLimit(w, r, "", 1) {
+			return
+		}
+		h.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// rateLimit takes n events from the limit of the client, when an operation is
+// set the limit of the client for that operation is used instead. It sets the
+// RateLimit headers and writes an error when the limit has been reached.
+func (s *graphjinService) rateLimit(w http.ResponseWriter, r *http.Request, op string, n int) bool {
+	if !s.conf.rateLimiterEnable() || n <= 0 {
+		return true
+	}
+
+	limit, res, ok, err := s.takeRateLimit(r, op, n)
+	if err != nil {
+		s.zlog.Error("Rate Limiter", []zapcore.Field{zap.Error(err)}...)
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		return true
+	}
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(limit.Bucket))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", seconds(res.Reset))
+
+	if !res.Allowed {
+		h.Set("Retry-After", seconds(res.RetryAfter))
+		http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// takeRateLimit takes n events from the limit of the client or the limit of
+// the client for the operation, ok is false when no limit applies
+func (s *graphjinService) takeRateLimit(r *http.Request, op string, n int) (
+	limit RateLimit, res RateLimitResult, ok bool, err error,
+) {
+	rl := &s.conf.RateLimiter
+
+	key, role, err := s.rateLimitKey(r)
+	if err != nil {
+		return
+	}
+
+	if op == "" {
+		limit, ok = rl.clientLimit(role)
+	} else {
+		limit, ok = rl.operationLimit(role, op)
+		key += "|op:" + op
+	}
+	if !ok {
+		return
+	}
+
+	res, err = s.rlStore.Allow(r.Context(), key, limit, n)
+	return
+}
+
+// rateLimitOp takes an event from the limit of the client for an operation
+func (s *graphjinService) rateLimitOp(w http.ResponseWriter, r *http.Request, op string) bool {
+	if op == "" {
+		return true
+	}
+	return s.rateLimit(w, r, op, 1)
+}
+
+// allowOp is similar to rateLimitOp except that it returns an error instead of
+// writing the response, it's used on connections that are already upgraded
+func (s *graphjinService) allowOp(r *http.Request, op string) error {
+	if op == "" || !s.conf.rateLimiterEnable() {
+		return nil
+	}
+
+	_, res, ok, err := s.takeRateLimit(r, op, 1)
+	if err != nil {
+		s.zlog.Error("Rate Limiter", []zapcore.Field{zap.Error(err)}...)
+		return errors.New("rate limiter error")
+	}
+	if ok && !res.Allowed {
+		return fmt.Errorf("too many requests, retry after %ss", seconds(res.RetryAfter))
+	}
+	return nil
+}
+
+// rateLimitKey returns the key that identifies the client and the role of the
+// client, unauthenticated clients and clients without a verified api key use their ip
+func (s *graphjinService) rateLimitKey(r *http.Request) (key, role string, err error) {
+	c := r.Context()
+	uid := c.Value(core.UserIDKey)
+
+	if v, ok := c.Value(core.UserRoleKey).(string); ok && v != "" {
+		role = v
+	} else if uid != nil {
+		role = "user"
+	} else {
+		role = "anon"
+	}
+
+	switch s.conf.RateLimiter.Key {
+	case "user":
+		if uid != nil {
+			key = fmt.Sprintf("user:%v", uid)
+			return
+		}
+
+	case "role":
+		key = "role:" + role
+		return
+
+	case "api_key":
+		// only keys verified by the api key auth are used so that
+		// clients cannot get a new limit by sending a random key
+		if id, ok := auth.APIKeyID(c); ok {
+			key = "key:" + id
+			return
+		}
+	}
+
+	ip, err := s.clientIP(r)
+	if err != nil {
+		return
+	}
+	key = "ip:" + ip
+	return
+}
+
+// clientLimit returns the limit for the requests of a role
+func (rl *RateLimiter) clientLimit(role string) (RateLimit, bool) {
+	for _, v := range rl.Limits {
+		if v.Operation == "" && v.Role == role {
+			return v, v.enabled()
+		}
+	}
+	v := RateLimit{Rate: rl.Rate, Bucket: rl.Bucket}
+	return v, v.enabled()
+}
+
+// operationLimit returns the limit for an operation, a limit
+// for the role takes precedence over one for all roles
+func (rl *RateLimiter) operationLimit(role, op string) (limit RateLimit, ok bool) {
+	for _, v := range rl.Limits {
+		if v.Operation != op {
+			continue
+		}
+		if v.Role == role {
+			return v, v.enabled()
+		}
+		if v.Role == "" {
+			limit, ok = v, v.enabled()
+		}
+	}
+	return
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0 && l.Bucket > 0
+}
+
+// memoryRateLimitStore is the default store, it uses the generic cell rate
+// algorithm (GCRA) which only needs a single time to be kept per key
+type memoryRateLimitStore struct {
+	sync.Mutex
+	tat   map[string]time.Time
+	swept time.Time
+}
+
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{tat: make(map[string]time.Time)}
+}
+
+func (ms *memoryRateLimitStore) Allow(c context.Context,
+	key string,
+	limit RateLimit,
+	n int,
+) (res RateLimitResult, err error) {
+	now := time.Now()
+
+	ms.Lock()
+	defer ms.Unlock()
+
+	// remove the keys whose limits have been fully reset
+	if now.Sub(ms.swept) > time.Minute {
+		for k, v := range ms.tat {
+			if v.Before(now) {
+				delete(ms.tat, k)
+			}
+		}
+		ms.swept = now
+	}
+
+	tat, ok := ms.tat[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+
+	res, tat = gcra(now, tat, limit, n)
+	ms.tat[key] = tat
+	return
+}
+
+// gcra takes n events from a limit using the theoretical arrival time (tat)
+// of the next event and returns the result and the new tat
+func gcra(now, tat time.Time, limit RateLimit, n int) (res RateLimitResult, newTAT time.Time) {
+	interval := time.Duration(float64(time.Second) / limit.Rate)
+	burst := interval * time.Duration(limit.Bucket)
+
+	newTAT = tat.Add(interval * time.Duration(n))
+	allowAt := newTAT.Add(-burst)
+
+	if now.Before(allowAt) {
+		res.RetryAfter = allowAt.Sub(now)
+		newTAT = tat
+	} else {
+		res.Allowed = true
+	}
+
+	res.Reset = newTAT.Sub(now)
+	res.Remaining = int((burst - res.Reset) / interval)
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	return
+}
+
+// seconds formats a duration as a whole number of seconds rounded up
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// clientIP returns the ip of the client from the ip header
+// or the remote address
+func (s *graphjinService) clientIP(r *http.Request) (ip string, err error) {
+	var iph string
+
+	if s.conf.RateLimiter.IPHeader != "" {
+		iph = r.Header.Get(s.conf.RateLimiter.IPHeader)
+	} else {
+		iph = r.Header.Get("X-Forwarded-For")
+	}
+
+	if iph != "" {
+		v := strings.Split(iph, ",")
+		switch n := len(v); {
+		case n > 1:
+			ip = strings.TrimSpace(v[n-2])
+		case n == 1:
+			ip = v[0]
+		}
+		return
+	}
+
+	ip, _, err = net.SplitHostPort(r.RemoteAddr)
+	return
+}
@@ -0,0 +1,104 @@
+package serv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dosco/graphjin/core/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Now()
+	limit := RateLimit{Rate: 1, Bucket: 2}
+
+	res, tat := gcra(now, now, limit, 1)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, tat = gcra(now, tat, limit, 1)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, tat1 := gcra(now, tat, limit, 1)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, tat, tat1)
+
+	res, _ = gcra(now.Add(time.Second), tat, limit, 1)
+	assert.True(t, res.Allowed)
+
+	res, _ = gcra(now, now, limit, 3)
+	assert.False(t, res.Allowed)
+
+	rl := RateLimiter{
+		Rate:   10,
+		Bucket: 20,
+		Limits: []RateLimit{
+			{Role: "admin", Rate: 100, Bucket: 200},
+			{Role: "anon"},
+			{Operation: "getUsers", Rate: 1, Bucket: 1},
+			{Operation: "getUsers", Role: "admin", Rate: 5, Bucket: 5},
+		},
+	}
+
+	l, ok := rl.clientLimit("admin")
+	assert.True(t, ok)
+	assert.Equal(t, 100.0, l.Rate)
+
+	l, ok = rl.clientLimit("user")
+	assert.True(t, ok)
+	assert.Equal(t, 10.0, l.Rate)
+
+	_, ok = rl.clientLimit("anon")
+	assert.False(t, ok)
+
+	l, ok = rl.operationLimit("admin", "getUsers")
+	assert.True(t, ok)
+	assert.Equal(t, 5.0, l.Rate)
+
+	l, ok = rl.operationLimit("user", "getUsers")
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, l.Rate)
+
+	_, ok = rl.operationLimit("user", "getPosts")
+	assert.False(t, ok)
+
+	s := &graphjinService{conf: &Config{}}
+	r := httptest.NewRequest("GET", "/api/v1/graphql", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-API-Key", "unverified")
+
+	c := context.WithValue(r.Context(), core.UserIDKey, "5")
+	c = context.WithValue(c, core.UserRoleKey, "admin")
+	ra := r.WithContext(c)
+
+	tests := []struct {
+		mode string
+		r    *http.Request
+		key  string
+		role string
+	}{
+		{"", r, "ip:10.0.0.1", "anon"},
+		{"user", r, "ip:10.0.0.1", "anon"},
+		{"user", ra, "user:5", "admin"},
+		{"role", ra, "role:admin", "admin"},
+		// an api key not verified by the api key auth is not used
+		{"api_key", r, "ip:10.0.0.1", "anon"},
+	}
+
+	for _, v := range tests {
+		s.conf.RateLimiter.Key = v.mode
+		key, role, err := s.rateLimitKey(v.r)
+		assert.NoError(t, err)
+		assert.Equal(t, v.key, key, v.mode)
+		assert.Equal(t, v.role, role, v.mode)
+	}
+
+	// the operation name is taken from the query
+	req := gqlReq{OpName: "getPosts", Query: "query getUsers { users { id } }"}
+	assert.Equal(t, "getUsers", s.opName(req))
+}
@@ -0,0 +1,263 @@
+package serv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dosco/graphjin/core/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// restRoute is a route from the config along with its parsed path
+type restRoute struct {
+	Route
+	segs []string
+}
+
+// newRESTRoutes validates the routes and groups them by path
+func newRESTRoutes(routes []Route) (paths []string, rmap map[string][]restRoute, err error) {
+	rmap = make(map[string][]restRoute)
+
+	for _, v := range routes {
+		v.Method = strings.ToUpper(v.Method)
+
+		switch v.Method {
+		case "GET", "POST", "PUT", "PATCH", "DELETE":
+		default:
+			return nil, nil, fmt.Errorf("routes: invalid method '%s' for path: %s", v.Method, v.Path)
+		}
+
+		if !strings.HasPrefix(v.Path, "/") {
+			return nil, nil, fmt.Errorf("routes: path must start with a '/': %s", v.Path)
+		}
+
+		if v.Query == "" {
+			return nil, nil, fmt.Errorf("routes: no query defined for: %s %s", v.Method, v.Path)
+		}
+
+		rl, ok := rmap[v.Path]
+		if !ok {
+			paths = append(paths, v.Path)
+		}
+
+		for _, rt := range rl {
+			if rt.Method == v.Method {
+				return nil, nil, fmt.Errorf("routes: duplicate route: %s %s", v.Method, v.Path)
+			}
+		}
+
+		rt := restRoute{Route: v, segs: strings.Split(strings.Trim(v.Path, "/"), "/")}
+		rmap[v.Path] = append(rl, rt)
+	}
+	return
+}
+
+// apiV1Route returns a handler that executes the named queries mapped to a path
+func (s1 *HttpService) apiV1Route(ns *string, routes []restRoute) http.Handler {
+	dtrace := otel.GetTextMapPropagator()
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		s := s1.Load().(*graphjinService)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		var rt *restRoute
+		for i := range routes {
+			if routes[i].Method == r.Method {
+				rt = &routes[i]
+				break
+			}
+		}
+
+		if rt == nil {
+			methods := make([]string, 0, len(routes))
+			for _, v := range routes {
+				methods = append(methods, v.Method)
+			}
+			sort.Strings(methods)
+
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			renderErr(w, fmt.Errorf("method not allowed: %s", r.Method))
+			return
+		}
+
+		if !s.rateLimitOp(w, r, rt.Query) {
+			return
+		}
+
+		ctx, opts := newDTrace(dtrace, r)
+		ctx, span := s.spanStart(ctx, "REST Route Request", opts...)
+		defer span.End()
+
+		vars, err := rt.vars(r)
+		if err != nil {
+			spanError(span, err)
+			w.WriteHeader(http.StatusBadRequest)
+			renderErr(w, err)
+			return
+		}
+
+		rc := s.newRequestConfig(r, gqlReq{}, ns)
+		res, err := s.gj.GraphQLByName(ctx, rt.Query, vars, &rc)
+
+		if span.IsRecording() {
+			span.SetAttributes(
+				attribute.String("http.path", r.RequestURI),
+				attribute.String("http.method", r.Method),
+				attribute.String("query.name", rt.Query))
+		}
+
+		if err != nil {
+			spanError(span, err)
+		}
+
+		status := restStatus(r, res, err)
+
+		if res == nil {
+			w.WriteHeader(status)
+			renderErr(w, err)
+			return
+		}
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+		}
+
+		s.responseHandler(
+			ctx,
+			w,
+			r,
+			start,
+			rc,
+			res,
+			err)
+	}
+	return http.HandlerFunc(h)
+}
+
+// vars returns the variables for the query, the fields of the request body
+// are overridden by the query string parameters, the headers and then the
+// path parameters
+func (rt *restRoute) vars(r *http.Request) (json.RawMessage, error) {
+	vm := make(map[string]json.RawMessage)
+
+	if r.Body != nil && r.Method != "GET" {
+		b, err := parseBody(r)
+		if err != nil {
+			return nil, err
+		}
+		b = bytes.TrimSpace(b)
+
+		switch {
+		case len(b) == 0:
+		case rt.Body != "":
+			if !json.Valid(b) {
+				return nil, errors.New("request body is not valid json")
+			}
+			vm[rt.Body] = b
+
+		default:
+			if err := json.Unmarshal(b, &vm); err != nil {
+				return nil, errors.New("request body must be a json object")
+			}
+		}
+	}
+
+	q := r.URL.Query()
+	for k, v := range rt.Params {
+		if val, ok := q[k]; ok && len(val) != 0 {
+			vm[v] = jsonString(val[0])
+		}
+	}
+
+	for k, v := range rt.Headers {
+		if val := r.Header.Get(k); val != "" {
+			vm[v] = jsonString(val)
+		}
+	}
+
+	// the route can be mounted under a prefix so match from the end, the
+	// escaped path is used so an encoded '/' stays within its parameter
+	ps := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	if len(ps) < len(rt.segs) {
+		return nil, fmt.Errorf("path does not match route: %s", rt.Path)
+	}
+	ps = ps[len(ps)-len(rt.segs):]
+
+	for i, seg := range rt.segs {
+		if len(seg) < 2 || seg[0] != '{' || seg[len(seg)-1] != '}' {
+			continue
+		}
+		name := seg[1 : len(seg)-1]
+
+		// strip the pattern from a chi style parameter {id:[0-9]+}
+		if n := strings.IndexByte(name, ':'); n != -1 {
+			name = name[:n]
+		}
+		val, err := url.PathUnescape(ps[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid path parameter: %s", name)
+		}
+		vm[name] = jsonString(val)
+	}
+
+	return json.Marshal(vm)
+}
+
+// restStatus returns the HTTP status code for the result of a query
+func restStatus(r *http.Request, res *core.Result, err error) int {
+	switch {
+	case res != nil && len(res.Validation) != 0:
+		return http.StatusUnprocessableEntity
+
+	case errors.Is(err, core.ErrUnknownQuery):
+		return http.StatusNotFound
+
+	case errors.Is(err, core.ErrInvalidVariable):
+		return http.StatusBadRequest
+
+	case errors.Is(err, errUnauthorized):
+		return http.StatusUnauthorized
+
+	case err != nil:
+		return http.StatusInternalServerError
+
+	case isNullResult(res.Data):
+		return http.StatusNotFound
+
+	case r.Method == "POST" && res.Operation() == core.OpMutation:
+		return http.StatusCreated
+	}
+	return http.StatusOK
+}
+
+// isNullResult returns true when all the fields of the result are null
+// for example when a single row was fetched and it was not found
+func isNullResult(data json.RawMessage) bool {
+	var dm map[string]json.RawMessage
+	if err := json.Unmarshal(data, &dm); err != nil || len(dm) == 0 {
+		return false
+	}
+
+	for _, v := range dm {
+		if !bytes.Equal(v, []byte("null")) {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonString(v string) json.RawMessage {
+	b, _ := json.Marshal(v)
+	return b
+}
@@ -0,0 +1,65 @@
+package serv
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRESTRoutes(t *testing.T) {
+	_, _, err := newRESTRoutes([]Route{{Method: "TRACE", Path: "/users", Query: "getUsers"}})
+	assert.Error(t, err)
+
+	_, _, err = newRESTRoutes([]Route{{Method: "GET", Path: "users", Query: "getUsers"}})
+	assert.Error(t, err)
+
+	_, _, err = newRESTRoutes([]Route{
+		{Method: "GET", Path: "/users", Query: "getUsers"},
+		{Method: "get", Path: "/users", Query: "getAllUsers"},
+	})
+	assert.Error(t, err)
+
+	paths, rmap, err := newRESTRoutes([]Route{
+		{
+			Method:  "put",
+			Path:    "/users/{id:[0-9]+}/posts/{slug}",
+			Query:   "updatePost",
+			Params:  map[string]string{"draft": "draft", "id": "id"},
+			Headers: map[string]string{"X-Slug": "slug"},
+		},
+		{Method: "POST", Path: "/users/{id}/posts/{slug}", Query: "createPost", Body: "data"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"/users/{id:[0-9]+}/posts/{slug}", "/users/{id}/posts/{slug}"}, paths)
+	assert.Equal(t, "PUT", rmap[paths[0]][0].Method)
+
+	// the path parameters override the query string, headers and body
+	rt := rmap[paths[0]][0]
+	r := httptest.NewRequest("PUT", "/api/users/5/posts/a%2F%22b?draft=true&id=6",
+		strings.NewReader(`{"id": 7, "title": "hello"}`))
+	r.Header.Set("X-Slug", "other")
+
+	vars, err := rt.vars(r)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id": "5", "slug": "a/\"b", "draft": "true", "title": "hello"}`, string(vars))
+
+	// the body is bound to a variable
+	rt = rmap[paths[1]][0]
+	r = httptest.NewRequest("POST", "/users/5/posts/b", strings.NewReader(`[1, 2]`))
+
+	vars, err = rt.vars(r)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id": "5", "slug": "b", "data": [1, 2]}`, string(vars))
+
+	r = httptest.NewRequest("POST", "/users/5/posts/b", strings.NewReader(`{"id": `))
+	_, err = rt.vars(r)
+	assert.Error(t, err)
+
+	r = httptest.NewRequest("POST", "/posts/b", nil)
+	_, err = rt.vars(r)
+	assert.Error(t, err)
+}
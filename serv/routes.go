@@ -9,7 +9,12 @@ import (
 const (
 	routeGraphQL = "/api/v1/graphql"
 	routeREST    = "/api/v1/rest/*"
+	routeOpenAPI = "/api/v1/openapi.json"
+	routeExport  = "/api/v1/export/*"
 	healthRoute  = "/health"
+
+	routeLoginCode  = "/api/v1/auth/code"
+	routeLoginToken = "/api/v1/auth/token"
 )
 
 type Mux interface {
@@ -30,11 +35,27 @@ func routesHandler(s1 *HttpService, mux Mux, ns *string) (http.Handler, error) {
 		mux.Handle(DeployRoute, adminDeployHandler(s1))
 	}
 
+	// API keys admin API
+	if s.apiKeys != nil && s.conf.AdminSecretKey != "" {
+		mux.Handle(APIKeysRoute, adminAPIKeysHandler(s1, "create"))
+		mux.Handle(APIKeysRotateRoute, adminAPIKeysHandler(s1, "rotate"))
+		mux.Handle(APIKeysRevokeRoute, adminAPIKeysHandler(s1, "revoke"))
+	}
+
+	// Built-in login
+	if s.login != nil {
+		mux.Handle(routeLoginCode, apiV1Handler(s1, ns, loginCodeHandler(s1), nil))
+		mux.Handle(routeLoginToken, apiV1Handler(s1, ns, loginTokenHandler(s1), nil))
+	}
+
 	if s.conf.WebUI {
 		mux.Handle("/*", s1.WebUI("/", routeGraphQL))
 	}
 
-	ah, err := auth.NewAuthHandlerFunc(s.conf.Auth)
+	ah, err := auth.NewAuthHandlerFunc(s.conf.Auth,
+		auth.OptionSetDB(s.db, s.conf.DBType),
+		auth.OptionSetAPIKeyStore(s.apiKeys),
+		auth.OptionSetSecrets(s.conf.GetSecretOrEnv))
 	if err != nil {
 		s.log.Fatalf("api: error initializing auth handler: %s", err)
 	}
@@ -51,6 +72,32 @@ func routesHandler(s1 *HttpService, mux Mux, ns *string) (http.Handler, error) {
 		mux.Handle(routeGraphQL, s1.GraphQLWithNS(ah, *ns))
 		mux.Handle(routeREST, s1.RESTWithNS(ah, *ns))
 	}
+	mux.Handle(routeOpenAPI, s1.OpenAPI(ah))
+
+	// Streaming export of named queries as NDJSON or CSV
+	if ns == nil {
+		mux.Handle(routeExport, s1.Export(ah))
+	} else {
+		mux.Handle(routeExport, s1.ExportWithNS(ah, *ns))
+	}
+
+	// REST routes mapped to named queries
+	paths, rmap, err := newRESTRoutes(s.conf.Routes)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		mux.Handle(p, apiV1Handler(s1, ns, s1.apiV1Route(ns, rmap[p]), ah))
+	}
+
+	// gRPC and Connect service generated from the named queries
+	if s.conf.GRPC.Enable {
+		route, h, err := s1.apiV1GRPC(ns)
+		if err != nil {
+			return nil, err
+		}
+		mux.Handle(route, apiV1Handler(s1, ns, h, ah))
+	}
 
 	return setServerHeader(mux), nil
 }
@@ -2,6 +2,9 @@ package serv
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
@@ -11,6 +14,8 @@ import (
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 var version string
@@ -56,9 +61,20 @@ func startHTTP(s1 *HttpService) {
 		s.log.Fatalf("error setting up routes: %s", err)
 	}
 
+	// gRPC needs HTTP/2 which without TLS is only available over h2c
+	if s.conf.GRPC.Enable {
+		routes = h2c.NewHandler(routes, &http2.Server{})
+	}
+
+	tc, err := s.tlsConfig()
+	if err != nil {
+		s.log.Fatalf("error setting up tls: %s", err)
+	}
+
 	s.srv = &http.Server{
 		Addr:              s.conf.hostPort,
 		Handler:           routes,
+		TLSConfig:         tc,
 		ReadTimeout:       10 * time.Second,
 		WriteTimeout:      10 * time.Second,
 		MaxHeaderBytes:    1 << 20,
@@ -102,6 +118,7 @@ func startHTTP(s1 *HttpService) {
 		zap.Bool("hot-deploy", s.conf.HotDeploy),
 		zap.Bool("production", s.conf.Core.Production),
 		zap.Bool("secrets-used", (s.conf.Serv.SecretsFile != "")),
+		zap.Bool("tls", (tc != nil)),
 	}
 
 	if s.namespace != nil {
@@ -122,12 +139,68 @@ func startHTTP(s1 *HttpService) {
 	// signal we are open for business.
 	s.state = servListening
 
-	if err := s.srv.Serve(l); err != http.ErrServerClosed {
+	if tc != nil {
+		err = s.srv.ServeTLS(l, "", "")
+	} else {
+		err = s.srv.Serve(l)
+	}
+	if err != http.ErrServerClosed {
 		s.log.Fatalf("failed to start: %s", err)
 	}
 	<-idleConnsClosed
 }
 
+// tlsConfig returns the tls config of the server, client certificates are
+// verified against the client ca bundle when it's set
+func (s *graphjinService) tlsConfig() (*tls.Config, error) {
+	c := s.conf.TLS
+
+	if c.CertFile == "" {
+		if c.KeyFile != "" || c.ClientCAFile != "" {
+			return nil, fmt.Errorf("tls.cert_file is required")
+		}
+		return nil, nil
+	}
+
+	if c.KeyFile == "" {
+		return nil, fmt.Errorf("tls.key_file is required")
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.conf.AbsolutePath(c.CertFile), s.conf.AbsolutePath(c.KeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.ClientCAFile == "" {
+		return tc, nil
+	}
+
+	b, err := os.ReadFile(s.conf.AbsolutePath(c.ClientCAFile))
+	if err != nil {
+		return nil, err
+	}
+
+	tc.ClientCAs = x509.NewCertPool()
+	if !tc.ClientCAs.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in tls.client_ca_file")
+	}
+
+	switch c.ClientAuth {
+	case "", "request":
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("tls.client_auth must be request or require: %s", c.ClientAuth)
+	}
+	return tc, nil
+}
+
 // Set the server header
 func setServerHeader(h http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,618 @@
+package serv
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dosco/graphjin/auth/v3"
+	"github.com/dosco/graphjin/core/v3"
+	"go.uber.org/zap"
+)
+
+// Server-sent events transport for the GraphQL over SSE protocol
+// https://github.com/enisdenjo/graphql-sse/blob/master/PROTOCOL.md
+
+const (
+	sseTokenHeader = "X-GraphQL-Event-Stream-Token"
+
+	// interval between keep-alive comments sent on idle streams
+	sseHeartbeat = 12 * time.Second
+
+	// time a reserved or disconnected stream is kept around
+	// for the client to (re)connect to
+	sseStreamTTL = 30 * time.Second
+
+	// number of events kept per stream for Last-Event-ID resume
+	sseMaxEvents = 100
+
+	// number of streams that can be reserved in total and per client
+	sseMaxStreams       = 10000
+	sseMaxClientStreams = 10
+
+	// number of operations that can run on a stream
+	sseMaxOps = 100
+)
+
+type sseEvent struct {
+	id    string
+	seq   uint64
+	event string
+	data  []byte
+}
+
+type sseNext struct {
+	ID      string  `json:"id"`
+	Payload Payload `json:"payload"`
+}
+
+type sseComplete struct {
+	ID string `json:"id"`
+}
+
+// sseStream is a stream reserved in single connection mode
+type sseStream struct {
+	mu     sync.Mutex
+	token  string
+	userID interface{}
+	client string
+	seq    uint64
+	events []sseEvent
+	ops    map[string]sseOp
+	open   bool
+	closed bool
+	notify chan struct{}
+	timer  *time.Timer
+}
+
+type sseOp struct {
+	m    *core.Member
+	done chan struct{}
+}
+
+type sseStreams struct {
+	mu      sync.Mutex
+	streams map[string]*sseStream
+}
+
+var (
+	errSSEStreamNotFound = errors.New("stream not found")
+	errSSETooManyStreams = errors.New("too many streams reserved")
+	errSSETooManyOps     = errors.New("too many operations on the stream")
+)
+
+// isSSE returns true for requests using the GraphQL over SSE protocol, these
+// either accept an event stream or use the token of a reserved stream. A
+// stream is reserved with a PUT that accepts the token as plain text.
+func isSSE(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+
+	switch {
+	case sseToken(r) != "":
+		return true
+	case strings.Contains(accept, "text/event-stream"):
+		return true
+	case r.Method == "PUT":
+		return strings.Contains(accept, "text/plain")
+	}
+	return false
+}
+
+func sseToken(r *http.Request) string {
+	if v := r.Header.Get(sseTokenHeader); v != "" {
+		return v
+	}
+	return r.URL.Query().Get("token")
+}
+
+// apiV1SSE handles both the distinct connections and the single
+// connection modes of the protocol
+func (s *graphjinService) apiV1SSE(w http.ResponseWriter, r *http.Request, ns *string) {
+	token := sseToken(r)
+
+	switch {
+	case r.Method == "PUT":
+		s.sseReserve(w, r)
+
+	case token == "":
+		s.sseDistinct(w, r, ns)
+
+	case r.Method == "GET":
+		s.sseListen(w, r, token)
+
+	case r.Method == "POST":
+		s.sseStart(w, r, ns, token)
+
+	case r.Method == "DELETE":
+		s.sseStop(w, r, token)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// sseDistinct streams the results of a single operation over its own
+// connection. Event ids are the hash of the result so a reconnecting
+// client is not sent a result it has already seen.
+func (s *graphjinService) sseDistinct(w http.ResponseWriter, r *http.Request, ns *string) {
+	req, err := parseGQLReq(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		renderErr(w, err)
+		return
+	}
+
+	if !s.rateLimitOp(w, r, s.opName(req)) {
+		return
+	}
+
+	c := r.Context()
+	rc := s.newRequestConfig(r, req, ns)
+
+	if h, _ := core.Operation(req.Query); h.Type != core.OpSubscription {
+		res, err := s.gj.GraphQL(c, req.Query, req.Vars, &rc)
+		if res == nil && err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			renderErr(w, err)
+			return
+		}
+		sw := newSSEWriter(w)
+		sw.write(sseEvent{event: "next", data: ssePayload("", res)}) //nolint:errcheck
+		sw.write(sseEvent{event: "complete"})                        //nolint:errcheck
+		return
+	}
+
+	m, err := s.gj.Subscribe(c, req.Query, req.Vars, &rc)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		renderErr(w, err)
+		return
+	}
+	defer m.Unsubscribe()
+
+	sw := newSSEWriter(w)
+	lastID := r.Header.Get("Last-Event-ID")
+
+	hb := time.NewTicker(sseHeartbeat)
+	defer hb.Stop()
+
+	for {
+		select {
+		case res := <-m.Result:
+			id := hex.EncodeToString(res.Hash[:])
+			if id == lastID {
+				continue
+			}
+			lastID = ""
+			err = sw.write(sseEvent{id: id, event: "next", data: ssePayload("", res)})
+
+		case <-hb.C:
+			err = sw.heartbeat()
+
+		case <-c.Done():
+			return
+		}
+
+		if err != nil {
+			s.zlog.Error("Subscription", zap.Error(err))
+			return
+		}
+	}
+}
+
+// sseReserve reserves a stream for the single connection mode and returns
+// the stream token. A reservation is removed if the client does not connect
+// to it within the stream ttl.
+func (s *graphjinService) sseReserve(w http.ResponseWriter, r *http.Request) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		renderErr(w, err)
+		return
+	}
+
+	userID := auth.UserID(r.Context())
+
+	// streams are limited per user or per ip for anonymous clients
+	client := fmt.Sprintf("user:%v", userID)
+	if userID == nil {
+		ip, err := s.clientIP(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			renderErr(w, err)
+			return
+		}
+		client = "ip:" + ip
+	}
+
+	st := &sseStream{
+		token:  hex.EncodeToString(b),
+		userID: userID,
+		client: client,
+		ops:    make(map[string]sseOp),
+		notify: make(chan struct{}, 1),
+	}
+
+	// the timer is set before the stream can be connected to
+	st.mu.Lock()
+	err := s.sse.add(st)
+	if err == nil {
+		st.timer = time.AfterFunc(sseStreamTTL, func() { s.sse.close(st) })
+	}
+	st.mu.Unlock()
+
+	if err != nil {
+		w.WriteHeader(http.StatusTooManyRequests)
+		renderErr(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(st.token)) //nolint:errcheck
+}
+
+// sseListen opens the event stream for a reserved token. Events are
+// numbered per stream and the ones after the Last-Event-ID are resent
+// on reconnect.
+func (s *graphjinService) sseListen(w http.ResponseWriter, r *http.Request, token string) {
+	st, err := s.sse.get(r, token)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		renderErr(w, err)
+		return
+	}
+
+	var sent uint64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if sent, err = strconv.ParseUint(v, 10, 64); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			renderErr(w, fmt.Errorf("invalid Last-Event-ID: %s", v))
+			return
+		}
+	}
+
+	if !st.connect() {
+		w.WriteHeader(http.StatusConflict)
+		renderErr(w, errors.New("stream already open"))
+		return
+	}
+	defer st.disconnect(sseStreamTTL, func() { s.sse.close(st) })
+
+	c := r.Context()
+	sw := newSSEWriter(w)
+
+	hb := time.NewTicker(sseHeartbeat)
+	defer hb.Stop()
+
+	for {
+		for _, ev := range st.since(sent) {
+			if err = sw.write(ev); err != nil {
+				return
+			}
+			sent = ev.seq
+		}
+
+		select {
+		case <-st.notify:
+		case <-hb.C:
+			err = sw.heartbeat()
+		case <-c.Done():
+			return
+		}
+
+		if err != nil || st.isClosed() {
+			return
+		}
+	}
+}
+
+// sseStart executes an operation with the results sent to the stream
+func (s *graphjinService) sseStart(w http.ResponseWriter, r *http.Request, ns *string, token string) {
+	st, err := s.sse.get(r, token)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		renderErr(w, err)
+		return
+	}
+
+	req, err := parseGQLReq(r)
+	if err == nil && req.Ext.OperationID == "" {
+		err = errors.New("extensions.operationId required")
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		renderErr(w, err)
+		return
+	}
+	opID := req.Ext.OperationID
+
+	if !s.rateLimitOp(w, r, s.opName(req)) {
+		return
+	}
+
+	// the operation runs longer than this request
+	c := context.WithoutCancel(r.Context())
+	rc := s.newRequestConfig(r, req, ns)
+
+	if h, _ := core.Operation(req.Query); h.Type != core.OpSubscription {
+		go func() {
+			res, err := s.gj.GraphQL(c, req.Query, req.Vars, &rc)
+			if res == nil && err != nil {
+				res = &core.Result{Errors: []core.Error{{Message: err.Error()}}}
+			}
+			st.push("next", ssePayload(opID, res))
+			st.push("complete", sseCompletePayload(opID))
+		}()
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	m, err := s.gj.Subscribe(c, req.Query, req.Vars, &rc)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		renderErr(w, err)
+		return
+	}
+
+	op := sseOp{m: m, done: make(chan struct{})}
+	if err := st.addOp(opID, op); err != nil {
+		m.Unsubscribe()
+		w.WriteHeader(http.StatusConflict)
+		renderErr(w, err)
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case res := <-m.Result:
+				st.push("next", ssePayload(opID, res))
+			case <-op.done:
+				return
+			}
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sseStop stops an operation running on the stream
+func (s *graphjinService) sseStop(w http.ResponseWriter, r *http.Request, token string) {
+	st, err := s.sse.get(r, token)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		renderErr(w, err)
+		return
+	}
+
+	opID := r.URL.Query().Get("operationId")
+	if st.removeOp(opID) {
+		st.push("complete", sseCompletePayload(opID))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func ssePayload(opID string, res *core.Result) []byte {
+	var v interface{}
+	p := Payload{Data: res.Data, Errors: res.Errors}
+
+	if opID != "" {
+		v = sseNext{ID: opID, Payload: p}
+	} else {
+		v = p
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(Payload{Errors: []core.Error{{Message: err.Error()}}})
+	}
+	return b
+}
+
+func sseCompletePayload(opID string) []byte {
+	b, _ := json.Marshal(sseComplete{ID: opID})
+	return b
+}
+
+func (ss *sseStreams) add(st *sseStream) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.streams == nil {
+		ss.streams = make(map[string]*sseStream)
+	}
+	if len(ss.streams) >= sseMaxStreams {
+		return errSSETooManyStreams
+	}
+
+	var n int
+	for _, v := range ss.streams {
+		if v.client == st.client {
+			n++
+		}
+	}
+	if n >= sseMaxClientStreams {
+		return errSSETooManyStreams
+	}
+
+	ss.streams[st.token] = st
+	return nil
+}
+
+// get returns the stream for the token if it belongs to the
+// same user that reserved it
+func (ss *sseStreams) get(r *http.Request, token string) (*sseStream, error) {
+	ss.mu.Lock()
+	st, ok := ss.streams[token]
+	ss.mu.Unlock()
+
+	if !ok {
+		return nil, errSSEStreamNotFound
+	}
+	if fmt.Sprint(st.userID) != fmt.Sprint(auth.UserID(r.Context())) {
+		return nil, errSSEStreamNotFound
+	}
+	return st, nil
+}
+
+// close removes a stream unless the client has reconnected to it
+func (ss *sseStreams) close(st *sseStream) {
+	st.mu.Lock()
+	if st.open {
+		st.mu.Unlock()
+		return
+	}
+	st.closed = true
+	for id, op := range st.ops {
+		op.stop()
+		delete(st.ops, id)
+	}
+	st.mu.Unlock()
+
+	ss.mu.Lock()
+	delete(ss.streams, st.token)
+	ss.mu.Unlock()
+}
+
+func (st *sseStream) push(event string, data []byte) {
+	st.mu.Lock()
+	st.seq++
+	ev := sseEvent{id: strconv.FormatUint(st.seq, 10), seq: st.seq, event: event, data: data}
+	st.events = append(st.events, ev)
+	if len(st.events) > sseMaxEvents {
+		st.events = st.events[1:]
+	}
+	st.mu.Unlock()
+
+	select {
+	case st.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (st *sseStream) since(seq uint64) (events []sseEvent) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for _, ev := range st.events {
+		if ev.seq > seq {
+			events = append(events, ev)
+		}
+	}
+	return
+}
+
+func (st *sseStream) connect() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.open || st.closed {
+		return false
+	}
+	st.open = true
+	st.timer.Stop()
+	return true
+}
+
+func (st *sseStream) disconnect(ttl time.Duration, fn func()) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.open = false
+	st.timer = time.AfterFunc(ttl, fn)
+}
+
+func (st *sseStream) isClosed() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.closed
+}
+
+func (st *sseStream) addOp(id string, op sseOp) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.closed {
+		return errSSEStreamNotFound
+	}
+	if _, ok := st.ops[id]; ok {
+		return fmt.Errorf("operation already exists: %s", id)
+	}
+	if len(st.ops) >= sseMaxOps {
+		return errSSETooManyOps
+	}
+	st.ops[id] = op
+	return nil
+}
+
+func (st *sseStream) removeOp(id string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	op, ok := st.ops[id]
+	if ok {
+		op.stop()
+		delete(st.ops, id)
+	}
+	return ok
+}
+
+func (op sseOp) stop() {
+	op.m.Unsubscribe()
+	close(op.done)
+}
+
+type sseWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+
+	rc := http.NewResponseController(w)
+
+	// streams outlive the server write timeout
+	rc.SetWriteDeadline(time.Time{}) //nolint:errcheck
+
+	w.WriteHeader(http.StatusOK)
+	rc.Flush() //nolint:errcheck
+
+	return &sseWriter{w: w, rc: rc}
+}
+
+func (sw *sseWriter) write(ev sseEvent) error {
+	var b bytes.Buffer
+	if ev.id != "" {
+		b.WriteString("id: " + ev.id + "\n")
+	}
+	b.WriteString("event: " + ev.event + "\n")
+	b.WriteString("data: ")
+	b.Write(ev.data)
+	b.WriteString("\n\n")
+
+	if _, err := sw.w.Write(b.Bytes()); err != nil {
+		return err
+	}
+	return sw.rc.Flush()
+}
+
+func (sw *sseWriter) heartbeat() error {
+	if _, err := sw.w.Write([]byte(":\n\n")); err != nil {
+		return err
+	}
+	return sw.rc.Flush()
+}
@@ -0,0 +1,96 @@
+package serv
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestSSE(t *testing.T) {
+	tests := []struct {
+		method, accept, token string
+		sse                   bool
+	}{
+		{"GET", "text/event-stream", "", true},
+		{"POST", "text/event-stream", "", true},
+		{"POST", "application/json", "", false},
+		{"PUT", "text/plain", "", true},
+		{"PUT", "application/json", "", false},
+		{"DELETE", "", "", false},
+		{"DELETE", "", "abc", true},
+		{"POST", "application/json", "abc", true},
+	}
+
+	for _, v := range tests {
+		r := httptest.NewRequest(v.method, "/api/v1/graphql", nil)
+		if v.accept != "" {
+			r.Header.Set("Accept", v.accept)
+		}
+		if v.token != "" {
+			r.Header.Set(sseTokenHeader, v.token)
+		}
+		assert.Equal(t, v.sse, isSSE(r), "%s %s %s", v.method, v.accept, v.token)
+	}
+
+	s := &graphjinService{conf: &Config{}}
+
+	reserve := func(ip string) int {
+		r := httptest.NewRequest("PUT", "/api/v1/graphql", nil)
+		r.Header.Set("Accept", "text/plain")
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		s.sseReserve(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < sseMaxClientStreams; i++ {
+		assert.Equal(t, http.StatusCreated, reserve("10.0.0.1"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, reserve("10.0.0.1"))
+	assert.Equal(t, http.StatusCreated, reserve("10.0.0.2"))
+
+	st := &sseStream{ops: make(map[string]sseOp)}
+	for i := 0; i < sseMaxOps; i++ {
+		assert.NoError(t, st.addOp(strconv.Itoa(i), sseOp{}))
+	}
+	assert.Error(t, st.addOp("0", sseOp{}))
+	assert.Equal(t, errSSETooManyOps, st.addOp("new", sseOp{}))
+
+	// the limits of the operations apply to both the modes
+	conf := &Config{}
+	conf.RateLimiter.Limits = []RateLimit{{Operation: "getUsers", Rate: 0.001, Bucket: 1}}
+	s = &graphjinService{conf: conf, zlog: zap.NewNop(), rlStore: newMemoryRateLimitStore()}
+
+	r := httptest.NewRequest("POST", "/api/v1/graphql", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	assert.NoError(t, s.allowOp(r, "getUsers"))
+
+	op := func(token string) int {
+		body := `{"query": "query getUsers { users { id } }", "extensions": {"operationId": "1"}}`
+		r := httptest.NewRequest("POST", "/api/v1/graphql", strings.NewReader(body))
+		r.Header.Set("Accept", "text/event-stream")
+		r.Header.Set("Content-Type", "application/json")
+		r.RemoteAddr = "10.0.0.1:1234"
+		if token != "" {
+			r.Header.Set(sseTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		s.apiV1SSE(w, r, nil)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusTooManyRequests, op(""))
+
+	r = httptest.NewRequest("PUT", "/api/v1/graphql", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	s.sseReserve(w, r)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, http.StatusTooManyRequests, op(w.Body.String()))
+}
+
+// nolint:errcheck
@@ -1,12 +1,13 @@
 package serv
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dosco/graphjin/auth/v3"
@@ -16,8 +17,23 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+const (
+	wsTransportProtocol = "graphql-transport-ws"
+	wsLegacyProtocol    = "graphql-ws"
+)
+
+// close codes defined by the graphql-transport-ws protocol
+const (
+	wsInvalidMessage   = 4400
+	wsUnauthorized     = 4401
+	wsForbidden        = 4403
+	wsInitTimeout      = 4408
+	wsSubscriberExists = 4409
+	wsTooManyInits     = 4429
+)
+
 type wsReq struct {
-	ID      string          `json:"id"`
+	ID      string          `json:"id,omitempty"`
 	Type    string          `json:"type,omitempty"`
 	Payload json.RawMessage `json:"payload,omitempty"`
 }
@@ -28,9 +44,27 @@ type wsRes struct {
 	Payload Payload `json:"payload"`
 }
 
+type wsErrorRes struct {
+	ID      string       `json:"id"`
+	Type    string       `json:"type"`
+	Payload []core.Error `json:"payload"`
+}
+
 type Payload struct {
-	Data   json.RawMessage `json:"data,omitempty"`
-	Errors []core.Error    `json:"errors,omitempty"`
+	Data        json.RawMessage    `json:"data,omitempty"`
+	Errors      []core.Error       `json:"errors,omitempty"`
+	Incremental []core.Incremental `json:"incremental,omitempty"`
+	HasNext     *bool              `json:"hasNext,omitempty"`
+}
+
+// wsCloseError closes the connection with a protocol close code
+type wsCloseError struct {
+	code   int
+	reason string
+}
+
+func (e *wsCloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.code, e.reason)
 }
 
 var upgrader = websocket.Upgrader{
@@ -38,30 +72,41 @@ var upgrader = websocket.Upgrader{
 	ReadBufferSize:    1024,
 	WriteBufferSize:   1024,
 	HandshakeTimeout:  10 * time.Second,
-	Subprotocols:      []string{"graphql-ws", "graphql-transport-ws"},
+	Subprotocols:      []string{wsLegacyProtocol, wsTransportProtocol},
 	CheckOrigin:       func(r *http.Request) bool { return true },
 }
 
-var initMsg *websocket.PreparedMessage
+var ackMsg, pingMsg, kaMsg *websocket.PreparedMessage
 
 func init() {
-	msg, err := json.Marshal(wsReq{ID: "1", Type: "connection_ack"})
+	ackMsg = newPreparedMsg(wsReq{Type: "connection_ack"})
+	pingMsg = newPreparedMsg(wsReq{Type: "ping"})
+	kaMsg = newPreparedMsg(wsReq{Type: "ka"})
+}
+
+func newPreparedMsg(m wsReq) *websocket.PreparedMessage {
+	msg, err := json.Marshal(m)
 	if err != nil {
 		panic(err)
 	}
 
-	initMsg, err = websocket.NewPreparedMessage(websocket.TextMessage, msg)
+	pm, err := websocket.NewPreparedMessage(websocket.TextMessage, msg)
 	if err != nil {
 		panic(err)
 	}
+	return pm
 }
 
 type wsConn struct {
 	c         context.Context
-	sessions  map[string]wsState
+	sessions  map[string]*wsState
+	sessMutex sync.Mutex
 	conn      *websocket.Conn
 	connMutex sync.Mutex
-	done      chan bool
+	done      chan struct{}
+	proto     string
+	init      atomic.Bool
+	ns        *string
 
 	w  http.ResponseWriter
 	r  *http.Request
@@ -69,31 +114,54 @@ type wsConn struct {
 }
 
 type wsState struct {
-	ID   string
-	m    *core.Member
-	done chan bool
+	ID     string
+	m      *core.Member
+	cancel context.CancelFunc
+	done   chan bool
 }
 
 // apiV1Ws handles the websocket connection
-func (s *graphjinService) apiV1Ws(w http.ResponseWriter, r *http.Request, ah auth.HandlerFunc) {
+func (s *graphjinService) apiV1Ws(w http.ResponseWriter, r *http.Request, ns *string, ah auth.HandlerFunc) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		renderErr(w, err)
 		return
 	}
 	defer conn.Close()
-	conn.SetReadLimit(2048)
+	conn.SetReadLimit(s.conf.WS.ReadLimit)
 
 	wc := wsConn{
 		c:        r.Context(),
-		sessions: make(map[string]wsState),
+		sessions: make(map[string]*wsState),
 		conn:     conn,
-		done:     make(chan bool),
+		done:     make(chan struct{}),
+		proto:    conn.Subprotocol(),
+		ns:       ns,
 		w:        w,
 		r:        r,
 		ah:       ah,
 	}
 
+	// the client has to initialize the connection in time
+	if wc.transport() && s.conf.WS.InitTimeout > 0 {
+		t := time.AfterFunc(s.conf.WS.InitTimeout, func() {
+			if !wc.init.Load() {
+				wc.close(wsInitTimeout, "Connection initialisation timeout")
+			}
+		})
+		defer t.Stop()
+	}
+
+	// close connections that stop responding to pings
+	if pi := s.conf.WS.PingInterval; pi > 0 {
+		wc.extendDeadline(pi)
+		conn.SetPongHandler(func(string) error {
+			wc.extendDeadline(pi)
+			return nil
+		})
+		go s.wsKeepAlive(&wc, pi)
+	}
+
 	for {
 		var b []byte
 		var req wsReq
@@ -102,24 +170,42 @@ func (s *graphjinService) apiV1Ws(w http.ResponseWriter, r *http.Request, ah aut
 			break
 		}
 
-		if err = json.Unmarshal(b, &req); err != nil {
-			break
+		if pi := s.conf.WS.PingInterval; pi > 0 {
+			wc.extendDeadline(pi)
 		}
 
-		if err = s.subSwitch(&wc, req); err != nil {
+		if err = json.Unmarshal(b, &req); err != nil || req.Type == "" {
+			err = &wsCloseError{wsInvalidMessage, "Invalid message received"}
+		} else {
+			err = s.subSwitch(&wc, req)
+		}
+
+		if err != nil {
+			var ce *wsCloseError
+			if errors.As(err, &ce) {
+				if wc.transport() {
+					wc.close(ce.code, ce.reason)
+				} else {
+					sendError(&wc, req.ID, errors.New(ce.reason)) //nolint:errcheck
+				}
+			}
 			break
 		}
 	}
 
 	if e, ok := err.(*websocket.CloseError); !ok ||
 		(e.Code != websocket.CloseNormalClosure && e.Code != websocket.CloseGoingAway) {
-		s.zlog.Error("Subscription", []zapcore.Field{zap.Error(err)}...)
+		s.zlog.Error("Websocket", []zapcore.Field{zap.Error(err)}...)
 	}
 
-	for _, st := range wc.sessions {
-		st.m.Unsubscribe()
+	wc.sessMutex.Lock()
+	for id, st := range wc.sessions {
+		wc.stop(st)
+		delete(wc.sessions, id)
 	}
-	wc.done <- true
+	wc.sessMutex.Unlock()
+
+	close(wc.done)
 }
 
 type authHeaders struct {
@@ -132,124 +218,291 @@ type authHeaders struct {
 func (s *graphjinService) subSwitch(wc *wsConn, req wsReq) (err error) {
 	switch req.Type {
 	case "connection_init":
+		if wc.init.Swap(true) {
+			return &wsCloseError{wsTooManyInits, "Too many initialisation requests"}
+		}
 		if err = setHeaders(req, wc.r); err != nil {
-			return
+			return &wsCloseError{wsInvalidMessage, "Invalid connection_init payload"}
 		}
-		if wc.c, err = wc.ah(wc.w, wc.r); err != nil {
-			return
+		var c context.Context
+		if c, err = wc.ah(wc.w, wc.r); err != nil {
+			return &wsCloseError{wsForbidden, "Forbidden"}
+		}
+		// no context is returned when the request has no credential
+		if c != nil {
+			wc.c = c
 		}
 		if s.conf.Serv.AuthFailBlock && !auth.IsAuth(wc.c) {
-			err = auth.Err401
-			return
+			return &wsCloseError{wsForbidden, "Forbidden"}
 		}
 
 		wc.connMutex.Lock()
-		err = wc.conn.WritePreparedMessage(initMsg)
+		err = wc.conn.WritePreparedMessage(ackMsg)
 		wc.connMutex.Unlock()
 
 		if err != nil {
 			return
 		}
 
+	case "ping":
+		msg := wsReq{Type: "pong", Payload: req.Payload}
+		if err = wc.write(msg); err != nil {
+			return
+		}
+
+	case "pong":
+		// keepalive reply, nothing to do
+
 	case "start", "subscribe":
+		if wc.transport() && !wc.init.Load() {
+			return &wsCloseError{wsUnauthorized, "Unauthorized"}
+		}
+		if req.ID == "" {
+			return &wsCloseError{wsInvalidMessage, "Operation id required"}
+		}
+
 		var p gqlReq
 		if err = json.Unmarshal(req.Payload, &p); err != nil {
-			break
+			return &wsCloseError{wsInvalidMessage, "Invalid subscribe payload"}
 		}
 
-		c := wc.c
-		if s.conf.Serv.Auth.Development {
-			var x authHeaders
-			if err = json.Unmarshal(p.Vars, &x); err != nil {
-				break
-			}
-			if x.UserIDProvider != "" {
-				c = context.WithValue(c, core.UserIDProviderKey, x.UserIDProvider)
-			}
-			if x.UserRole != "" {
-				c = context.WithValue(c, core.UserRoleKey, x.UserRole)
-			}
-			if x.UserID != nil {
-				c = context.WithValue(c, core.UserIDKey, x.UserID)
-			}
-		}
+		wc.sessMutex.Lock()
+		_, exists := wc.sessions[req.ID]
+		wc.sessMutex.Unlock()
 
-		st := wsState{ID: req.ID, done: make(chan bool)}
-		if st.m, err = s.gj.Subscribe(c, p.Query, p.Vars, nil); err != nil {
-			break
+		if exists {
+			return &wsCloseError{wsSubscriberExists,
+				fmt.Sprintf("Subscriber for %s already exists", req.ID)}
 		}
-		wc.sessions[st.ID] = st
-		useNext := req.Type == "subscribe"
 
-		go s.waitForData(wc, &st, useNext)
+		if err = s.wsOperation(wc, req, p); err != nil {
+			sendError(wc, req.ID, err) //nolint:errcheck
+			err = nil
+		}
 
-	case "complete", "connection_terminate", "stop":
+	case "complete", "stop":
+		wc.sessMutex.Lock()
 		if st, ok := wc.sessions[req.ID]; ok {
-			st.done <- true
-			st.m.Unsubscribe()
+			wc.stop(st)
 			delete(wc.sessions, req.ID)
 		}
+		wc.sessMutex.Unlock()
+
+	case "connection_terminate":
+		err = &websocket.CloseError{Code: websocket.CloseNormalClosure}
 
 	default:
-		err = fmt.Errorf("unknown message type: %s", req.Type)
+		err = &wsCloseError{wsInvalidMessage,
+			fmt.Sprintf("Unknown message type: %s", req.Type)}
+	}
+	return
+}
+
+// wsOperation starts a query, mutation or subscription
+func (s *graphjinService) wsOperation(wc *wsConn, req wsReq, p gqlReq) (err error) {
+	c := wc.c
+	if s.conf.Serv.Auth.Development {
+		var x authHeaders
+		if len(p.Vars) != 0 {
+			if err = json.Unmarshal(p.Vars, &x); err != nil {
+				return
+			}
+		}
+		if x.UserIDProvider != "" {
+			c = context.WithValue(c, core.UserIDProviderKey, x.UserIDProvider)
+		}
+		if x.UserRole != "" {
+			c = context.WithValue(c, core.UserRoleKey, x.UserRole)
+		}
+		if x.UserID != nil {
+			c = context.WithValue(c, core.UserIDKey, x.UserID)
+		}
 	}
 
+	ptype := "data"
+	if req.Type == "subscribe" {
+		ptype = "next"
+	}
+
+	h, err := core.Operation(p.Query)
 	if err != nil {
-		sendError(wc, req.ID, err) //nolint:errcheck
+		return
+	}
+
+	r := wc.r.WithContext(c)
+	if err = s.allowOp(r, s.opName(p)); err != nil {
+		return
 	}
+	rc := s.newRequestConfig(r, p, wc.ns)
+
+	st := &wsState{ID: req.ID, done: make(chan bool, 1)}
+
+	// queries and mutations return a single result followed
+	// by the results of any deferred parts
+	if h.Type == core.OpQuery || h.Type == core.OpMutation {
+		c, st.cancel = context.WithCancel(c)
+		wc.add(st)
+
+		go s.wsQuery(c, wc, st, p, rc, ptype)
+		return
+	}
+
+	if st.m, err = s.gj.Subscribe(c, p.Query, p.Vars, &rc); err != nil {
+		return
+	}
+	wc.add(st)
+
+	go s.waitForData(wc, st, ptype)
 	return
 }
 
 // waitForData waits for data from the subscription
-func (s *graphjinService) waitForData(wc *wsConn, st *wsState, useNext bool) {
-	var buf bytes.Buffer
+func (s *graphjinService) waitForData(wc *wsConn, st *wsState, ptype string) {
+	for {
+		select {
+		case v := <-st.m.Result:
+			if err := sendResult(wc, st.ID, ptype, v); err != nil {
+				s.zlog.Error("Subscription", []zapcore.Field{zap.Error(err)}...)
+				return
+			}
 
-	var ptype string
-	var err error
+		case <-st.done:
+			return
 
-	if useNext {
-		ptype = "next"
-	} else {
-		ptype = "data"
+		case <-wc.done:
+			return
+		}
+	}
+}
+
+// wsQuery runs a query or mutation and sends the initial and
+// incremental results followed by a complete message
+func (s *graphjinService) wsQuery(c context.Context,
+	wc *wsConn,
+	st *wsState,
+	p gqlReq,
+	rc core.RequestConfig,
+	ptype string,
+) {
+	defer wc.remove(st)
+
+	res, next, err := s.gj.GraphQLIncremental(c, p.Query, p.Vars, &rc)
+	if res == nil && err != nil {
+		sendError(wc, st.ID, err) //nolint:errcheck
+		return
+	}
+
+	if err = sendResult(wc, st.ID, ptype, res); err != nil {
+		s.zlog.Error("Query", []zapcore.Field{zap.Error(err)}...)
+		return
+	}
+
+	for v := range next {
+		if c.Err() != nil {
+			return
+		}
+		if err = sendResult(wc, st.ID, ptype, v); err != nil {
+			s.zlog.Error("Query", []zapcore.Field{zap.Error(err)}...)
+			return
+		}
 	}
 
-	enc := json.NewEncoder(&buf)
+	// the client completed the operation
+	if c.Err() != nil {
+		return
+	}
+	wc.write(wsReq{ID: st.ID, Type: "complete"}) //nolint:errcheck
+}
+
+// wsKeepAlive sends pings to keep the connection alive
+func (s *graphjinService) wsKeepAlive(wc *wsConn, pi time.Duration) {
+	t := time.NewTicker(pi)
+	defer t.Stop()
+
 	for {
 		select {
-		case v := <-st.m.Result:
-			res := wsRes{ID: st.ID, Type: ptype}
-			res.Payload.Data = v.Data
-			res.Payload.Errors = v.Errors
+		case <-t.C:
+			dl := time.Now().Add(pi)
+			if err := wc.conn.WriteControl(websocket.PingMessage, nil, dl); err != nil {
+				return
+			}
+
+			if !wc.init.Load() {
+				continue
+			}
 
-			if err = enc.Encode(res); err != nil {
-				break
+			pm := kaMsg
+			if wc.transport() {
+				pm = pingMsg
 			}
-			msg := buf.Bytes()
-			buf.Reset()
 
 			wc.connMutex.Lock()
-			err = wc.conn.WriteMessage(websocket.TextMessage, msg)
+			err := wc.conn.WritePreparedMessage(pm)
 			wc.connMutex.Unlock()
 
 			if err != nil {
-				s.zlog.Error("Subscription", []zapcore.Field{zap.Error(err)}...)
-				sendError(wc, st.ID, err) //nolint:errcheck
-				break
-			}
-
-		case v := <-st.done:
-			if v {
 				return
 			}
 
-		case v := <-wc.done:
-			if v {
-				return
-			}
+		case <-wc.done:
+			return
 		}
 	}
 }
 
+// transport returns true when the client uses the graphql-transport-ws protocol
+func (wc *wsConn) transport() bool {
+	return wc.proto == wsTransportProtocol
+}
+
+func (wc *wsConn) add(st *wsState) {
+	wc.sessMutex.Lock()
+	wc.sessions[st.ID] = st
+	wc.sessMutex.Unlock()
+}
+
+func (wc *wsConn) remove(st *wsState) {
+	wc.sessMutex.Lock()
+	if v, ok := wc.sessions[st.ID]; ok && v == st {
+		delete(wc.sessions, st.ID)
+	}
+	wc.sessMutex.Unlock()
+}
+
+// stop ends an operation, it must be called with the sessions lock held
+func (wc *wsConn) stop(st *wsState) {
+	if st.cancel != nil {
+		st.cancel()
+	}
+	if st.m != nil {
+		st.done <- true
+		st.m.Unsubscribe()
+	}
+}
+
+func (wc *wsConn) write(m wsReq) (err error) {
+	msg, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+
+	wc.connMutex.Lock()
+	defer wc.connMutex.Unlock()
+	err = wc.conn.WriteMessage(websocket.TextMessage, msg)
+	return
+}
+
+func (wc *wsConn) extendDeadline(pi time.Duration) {
+	wc.conn.SetReadDeadline(time.Now().Add(pi * 2)) //nolint:errcheck
+}
+
+// close sends a close message with the code and reason and closes the connection
+func (wc *wsConn) close(code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	wc.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)) //nolint:errcheck
+	wc.conn.Close()
+}
+
 // setHeaders sets the headers from the payload
 func setHeaders(req wsReq, r *http.Request) (err error) {
 	if len(req.Payload) == 0 {
@@ -270,10 +523,13 @@ func setHeaders(req wsReq, r *http.Request) (err error) {
 	return
 }
 
-// sendError sends an error message to the client
-func sendError(wc *wsConn, id string, cerr error) (err error) {
-	m := wsRes{ID: id, Type: "error"}
-	m.Payload.Errors = []core.Error{{Message: cerr.Error()}}
+// sendResult sends a result as a data or next message
+func sendResult(wc *wsConn, id, ptype string, v *core.Result) (err error) {
+	m := wsRes{ID: id, Type: ptype}
+	m.Payload.Data = v.Data
+	m.Payload.Errors = v.Errors
+	m.Payload.Incremental = v.Incremental
+	m.Payload.HasNext = v.HasNext
 
 	msg, err := json.Marshal(m)
 	if err != nil {
@@ -285,3 +541,27 @@ func sendError(wc *wsConn, id string, cerr error) (err error) {
 	err = wc.conn.WriteMessage(websocket.TextMessage, msg)
 	return
 }
+
+// sendError sends an error message to the client, with graphql-transport-ws
+// the payload is a list of errors
+func sendError(wc *wsConn, id string, cerr error) (err error) {
+	var msg []byte
+
+	if wc.transport() {
+		m := wsErrorRes{ID: id, Type: "error"}
+		m.Payload = []core.Error{{Message: cerr.Error()}}
+		msg, err = json.Marshal(m)
+	} else {
+		m := wsRes{ID: id, Type: "error"}
+		m.Payload.Errors = []core.Error{{Message: cerr.Error()}}
+		msg, err = json.Marshal(m)
+	}
+	if err != nil {
+		return
+	}
+
+	wc.connMutex.Lock()
+	defer wc.connMutex.Unlock()
+	err = wc.conn.WriteMessage(websocket.TextMessage, msg)
+	return
+}
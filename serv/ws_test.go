@@ -0,0 +1,54 @@
+package serv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestWS(t *testing.T) {
+	conf := &Config{}
+	conf.RateLimiter.Limits = []RateLimit{{Operation: "getUsers", Rate: 0.001, Bucket: 1}}
+
+	s := &graphjinService{conf: conf, zlog: zap.NewNop(), rlStore: newMemoryRateLimitStore()}
+
+	// the auth returns no context when the request has no credential
+	ah := func(_ http.ResponseWriter, _ *http.Request) (context.Context, error) {
+		return nil, nil
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.apiV1Ws(w, r, nil, ah)
+	}))
+	defer ts.Close()
+
+	d := websocket.Dialer{Subprotocols: []string{wsTransportProtocol}}
+	conn, _, err := d.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the limit of the operation is used up by an earlier request of the client
+	r := httptest.NewRequest("GET", "/api/v1/graphql", nil)
+	r.RemoteAddr = "127.0.0.1:1234"
+	assert.NoError(t, s.allowOp(r, "getUsers"))
+
+	var res map[string]interface{}
+
+	assert.NoError(t, conn.WriteJSON(wsReq{Type: "connection_init"}))
+	assert.NoError(t, conn.ReadJSON(&res))
+	assert.Equal(t, "connection_ack", res["type"])
+
+	op := wsReq{ID: "1", Type: "subscribe", Payload: []byte(`{"query":"query getUsers { users { id } }"}`)}
+	assert.NoError(t, conn.WriteJSON(op))
+	assert.NoError(t, conn.ReadJSON(&res))
+	assert.Equal(t, "error", res["type"])
+	assert.Equal(t, "1", res["id"])
+	assert.Contains(t, res["payload"].([]interface{})[0].(map[string]interface{})["message"], "too many requests")
+}
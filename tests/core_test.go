@@ -152,6 +152,60 @@ func TestAllowList(t *testing.T) {
 	assert.ErrorContains(t, err, "unknown graphql query")
 }
 
+func TestExportByName(t *testing.T) {
+	gql := `
+	query getProducts {
+		products(limit: 3, order_by: { id: asc }) {
+			id
+		}
+	}`
+
+	dir, err := os.MkdirTemp("", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := core.NewOsFS(dir)
+	err = fs.Put("queries/getProducts.gql", []byte(gql))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	conf := newConfig(&core.Config{DBType: dbType, Production: true})
+	gj, err := core.NewGraphJin(conf, db, core.OptionSetFS(fs))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var exp testExporter
+	_, err = gj.ExportByName(context.Background(), "getProducts", nil, nil, &exp)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id"}, exp.cols)
+
+	if assert.Len(t, exp.rows, 3) {
+		assert.JSONEq(t, `{"id": 1}`, exp.rows[0])
+		assert.JSONEq(t, `{"id": 3}`, exp.rows[2])
+	}
+}
+
+type testExporter struct {
+	cols []string
+	rows []string
+}
+
+func (e *testExporter) Columns(cols []string) error {
+	e.cols = cols
+	return nil
+}
+
+func (e *testExporter) Row(row json.RawMessage) error {
+	e.rows = append(e.rows, string(row))
+	return nil
+}
+
 func TestAllowListWithNamespace(t *testing.T) {
 	gql1 := `
 	fragment Product on products {